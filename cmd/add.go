@@ -6,6 +6,7 @@ import (
 	"github.com/spf13/cobra"
 	"gossh/internal/config"
 	"gossh/internal/i18n"
+	"gossh/internal/ssh"
 	"os"
 	"strconv"
 	"strings"
@@ -33,8 +34,12 @@ func runFlagBasedAdd(cmd *cobra.Command) {
 	port, _ := cmd.Flags().GetInt("port")
 	keyPath, _ := cmd.Flags().GetString("key")
 	credAlias, _ := cmd.Flags().GetString("use-password")
+	limit, _ := cmd.Flags().GetString("limit")
+	controlPersist, _ := cmd.Flags().GetString("control-persist")
+	proxyJump, _ := cmd.Flags().GetString("proxy-jump")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
 
-	if name == "" || user == "" || host == "" {
+	if name == "" || host == "" {
 		fmt.Println(i18n.T("add.error.name.user.host.required"))
 		os.Exit(1)
 	}
@@ -48,6 +53,18 @@ func runFlagBasedAdd(cmd *cobra.Command) {
 		os.Exit(1)
 	}
 
+	if limit != "" {
+		if _, err := ssh.ParseBandwidth(limit); err != nil {
+			fmt.Println(i18n.TWith("scp.error.invalid.limit", map[string]interface{}{"Limit": limit}))
+			os.Exit(1)
+		}
+	}
+
+	if _, _, err := ssh.ParseControlPersist(controlPersist); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
 	conn := config.Connection{
 		Name:            name,
 		Group:           group,
@@ -56,6 +73,21 @@ func runFlagBasedAdd(cmd *cobra.Command) {
 		Port:            port,
 		KeyPath:         keyPath,
 		CredentialAlias: credAlias,
+		BandwidthLimit:  limit,
+		ControlPersist:  controlPersist,
+		ProxyJump:       proxyJump,
+		Tags:            checkTags(tags),
+	}
+
+	// The user may come from the group or global defaults.
+	doc, err := config.LoadDocument()
+	if err != nil {
+		fmt.Println(i18n.TWith("error.loading.connections", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+	if doc.Resolve(conn).User == "" {
+		fmt.Println(i18n.T("add.error.name.user.host.required"))
+		os.Exit(1)
 	}
 
 	if err := config.AddConnection(conn); err != nil {
@@ -88,9 +120,7 @@ func runInteractiveAdd() {
 	fmt.Print(i18n.T("add.enter.port"))
 	portStr, _ := reader.ReadString('\n')
 	portStr = strings.TrimSpace(portStr)
-	if portStr == "" {
-		conn.Port = 22
-	} else {
+	if portStr != "" {
 		port, err := strconv.Atoi(portStr)
 		if err != nil {
 			fmt.Println(i18n.T("add.invalid.port"))
@@ -157,8 +187,12 @@ func init() {
 	addCmd.Flags().StringP("group", "g", "", i18n.T("add.flag.group"))
 	addCmd.Flags().StringP("user", "u", "", i18n.T("add.flag.user"))
 	addCmd.Flags().StringP("host", "H", "", i18n.T("add.flag.host"))
-	addCmd.Flags().IntP("port", "p", 22, i18n.T("add.flag.port"))
+	addCmd.Flags().IntP("port", "p", 0, i18n.T("add.flag.port"))
 	addCmd.Flags().StringP("key", "k", "", i18n.T("add.flag.key"))
 	addCmd.Flags().StringP("use-password", "P", "", i18n.T("add.flag.use-password"))
+	addCmd.Flags().String("limit", "", i18n.T("add.flag.limit"))
+	addCmd.Flags().String("control-persist", "", i18n.T("add.flag.control.persist"))
+	addCmd.Flags().StringP("proxy-jump", "J", "", i18n.T("add.flag.proxy.jump"))
+	addCmd.Flags().StringSliceP("tag", "t", nil, i18n.T("add.flag.tag"))
 	addCmd.Flags().BoolP("interactive", "i", false, i18n.T("add.flag.interactive"))
 }
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/spf13/cobra"
+	"gossh/internal/i18n"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit <name>",
+	Short: i18n.T("config.edit.short"),
+	Long:  i18n.T("config.edit.long"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		doc, conn := loadConnection(args[0])
+		reader := bufio.NewReader(os.Stdin)
+
+		conn.Group = promptField(reader, i18n.T("config.edit.group"), conn.Group)
+		conn.User = promptField(reader, i18n.T("config.edit.user"), conn.User)
+		conn.Host = promptField(reader, i18n.T("config.edit.host"), conn.Host)
+
+		port := ""
+		if conn.Port != 0 {
+			port = strconv.Itoa(conn.Port)
+		}
+		port = promptField(reader, i18n.T("config.edit.port"), port)
+		if port == "" {
+			conn.Port = 0
+		} else {
+			p, err := strconv.Atoi(port)
+			if err != nil {
+				fmt.Println(i18n.T("add.invalid.port"))
+				os.Exit(1)
+			}
+			conn.Port = p
+		}
+
+		keyPath := promptField(reader, i18n.T("config.edit.key"), conn.KeyPath)
+		credAlias := promptField(reader, i18n.T("config.edit.password"), conn.CredentialAlias)
+		if keyPath != "" && credAlias != "" {
+			fmt.Println(i18n.T("add.error.key.password.together"))
+			os.Exit(1)
+		}
+		conn.KeyPath, conn.CredentialAlias = keyPath, credAlias
+
+		conn.BandwidthLimit = promptField(reader, i18n.T("config.edit.limit"), conn.BandwidthLimit)
+		conn.ControlPersist = promptField(reader, i18n.T("config.edit.control.persist"), conn.ControlPersist)
+		conn.ProxyJump = promptField(reader, i18n.T("config.edit.proxy.jump"), conn.ProxyJump)
+		tags := promptField(reader, i18n.T("config.edit.tags"), strings.Join(conn.Tags, ","))
+		conn.Tags = checkTags(strings.Split(tags, ","))
+
+		saveUpdatedConnection(doc, *conn)
+	},
+}
+
+// promptField asks for a new value showing the current one. An empty answer keeps the
+// current value and "-" clears it.
+func promptField(reader *bufio.Reader, label, current string) string {
+	fmt.Printf("%s [%s]: ", label, current)
+	answer, _ := reader.ReadString('\n')
+	switch answer = strings.TrimSpace(answer); answer {
+	case "":
+		return current
+	case "-":
+		return ""
+	}
+	return answer
+}
+
+func init() {
+	configCmd.AddCommand(configEditCmd)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"os"
+	"strings"
+)
+
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: i18n.T("convert.short"),
+	Long:  i18n.T("convert.long"),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		to, _ := cmd.Flags().GetString("to")
+		to = strings.ToLower(to)
+		if to == "yml" {
+			to = config.FormatYAML
+		}
+		valid := false
+		for _, f := range config.Formats {
+			valid = valid || f == to
+		}
+		if !valid {
+			fmt.Println(i18n.TWith("convert.error.invalid.format", map[string]interface{}{
+				"Format":  to,
+				"Formats": strings.Join(config.Formats, ", "),
+			}))
+			os.Exit(1)
+		}
+
+		oldPath := config.ConfigFile()
+		path, err := config.Convert(to)
+		if err != nil {
+			fmt.Println(i18n.TWith("convert.error", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+		fmt.Println(i18n.TWith("convert.success", map[string]interface{}{"Old": oldPath, "New": path}))
+	},
+}
+
+func init() {
+	convertCmd.Flags().String("to", "", i18n.T("convert.flag.to"))
+	convertCmd.MarkFlagRequired("to")
+	configCmd.AddCommand(convertCmd)
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"os"
+)
+
+var defaultsCmd = &cobra.Command{
+	Use:   "defaults",
+	Short: i18n.T("defaults.short"),
+	Long:  i18n.T("defaults.long"),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		group, _ := cmd.Flags().GetString("group")
+		flags := cmd.Flags()
+
+		if !flags.Changed("user") && !flags.Changed("port") && !flags.Changed("key") &&
+			!flags.Changed("use-password") && !flags.Changed("limit") &&
+			!flags.Changed("control-persist") && !flags.Changed("proxy-jump") {
+			doc, err := config.LoadDocument()
+			if err != nil {
+				fmt.Println(i18n.TWith("error.loading.connections", map[string]interface{}{"Error": err}))
+				os.Exit(1)
+			}
+			printDefaults(doc, group)
+			return
+		}
+
+		if flags.Changed("key") && flags.Changed("use-password") {
+			keyPath, _ := flags.GetString("key")
+			credAlias, _ := flags.GetString("use-password")
+			if keyPath != "" && credAlias != "" {
+				fmt.Println(i18n.T("add.error.key.password.together"))
+				os.Exit(1)
+			}
+		}
+
+		err := config.ModifyDocument(func(doc *config.Document) error {
+			d := doc.Defaults
+			if group != "" {
+				d = doc.Groups[group]
+			}
+			if flags.Changed("user") {
+				d.User, _ = flags.GetString("user")
+			}
+			if flags.Changed("port") {
+				d.Port, _ = flags.GetInt("port")
+			}
+			if flags.Changed("key") {
+				d.KeyPath, _ = flags.GetString("key")
+				if d.KeyPath != "" {
+					d.CredentialAlias = ""
+				}
+			}
+			if flags.Changed("use-password") {
+				d.CredentialAlias, _ = flags.GetString("use-password")
+				if d.CredentialAlias != "" {
+					d.KeyPath = ""
+				}
+			}
+			if flags.Changed("limit") {
+				d.BandwidthLimit, _ = flags.GetString("limit")
+			}
+			if flags.Changed("control-persist") {
+				d.ControlPersist, _ = flags.GetString("control-persist")
+			}
+			if flags.Changed("proxy-jump") {
+				d.ProxyJump, _ = flags.GetString("proxy-jump")
+			}
+			validateSettings(d)
+
+			switch {
+			case group == "":
+				doc.Defaults = d
+			case d.IsZero():
+				delete(doc.Groups, group)
+			default:
+				if doc.Groups == nil {
+					doc.Groups = make(map[string]config.Defaults)
+				}
+				doc.Groups[group] = d
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Println(i18n.TWith("defaults.error.saving", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+		if group != "" {
+			fmt.Println(i18n.TWith("defaults.group.updated", map[string]interface{}{"Group": group}))
+		} else {
+			fmt.Println(i18n.T("defaults.updated"))
+		}
+	},
+}
+
+// printDefaults prints the global defaults, or those of one group.
+func printDefaults(doc *config.Document, group string) {
+	d := doc.Defaults
+	if group != "" {
+		d = doc.Groups[group]
+		fmt.Println(i18n.TWith("defaults.group.header", map[string]interface{}{"Group": group}))
+	} else {
+		fmt.Println(i18n.T("defaults.header"))
+	}
+	if d.IsZero() {
+		fmt.Println(i18n.T("defaults.none"))
+		return
+	}
+	printSettings(d, nil, group)
+}
+
+func init() {
+	defaultsCmd.Flags().StringP("group", "g", "", i18n.T("defaults.flag.group"))
+	defaultsCmd.Flags().StringP("user", "u", "", i18n.T("add.flag.user"))
+	defaultsCmd.Flags().IntP("port", "p", 0, i18n.T("add.flag.port"))
+	defaultsCmd.Flags().StringP("key", "k", "", i18n.T("add.flag.key"))
+	defaultsCmd.Flags().StringP("use-password", "P", "", i18n.T("add.flag.use-password"))
+	defaultsCmd.Flags().String("limit", "", i18n.T("add.flag.limit"))
+	defaultsCmd.Flags().String("control-persist", "", i18n.T("add.flag.control.persist"))
+	defaultsCmd.Flags().StringP("proxy-jump", "J", "", i18n.T("add.flag.proxy.jump"))
+	configCmd.AddCommand(defaultsCmd)
+}
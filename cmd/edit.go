@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"gossh/internal/ssh"
+	"os"
+	"strings"
+)
+
+var editCmd = &cobra.Command{
+	Use:   "edit <name>:<path>",
+	Short: i18n.T("edit.short"),
+	Long:  i18n.T("edit.long"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		parts := strings.SplitN(args[0], ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			fmt.Println(i18n.T("scp.error.invalid.format"))
+			os.Exit(1)
+		}
+		connectionName := parts[0]
+		remotePath := parts[1]
+
+		connections, err := config.LoadConnections()
+		if err != nil {
+			fmt.Println(i18n.TWith("error.loading.connections", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+
+		conn := findConnection(connections, connectionName)
+		if conn == nil {
+			fmt.Println(i18n.TWith("error.connection.not.found", map[string]interface{}{"Name": connectionName}))
+			os.Exit(1)
+		}
+
+		if err := ssh.EditRemoteFile(conn, remotePath); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
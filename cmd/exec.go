@@ -14,10 +14,9 @@ var execCmd = &cobra.Command{
 	Use:   "exec <name> <command>",
 	Short: i18n.T("exec.short"),
 	Long:  i18n.T("exec.long"),
-	Args:  cobra.MinimumNArgs(2),
+	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		connectionName := args[0]
-		command := strings.Join(args[1:], " ")
+		selector, _ := cmd.Flags().GetString("selector")
 
 		connections, err := config.LoadConnections()
 		if err != nil {
@@ -25,6 +24,17 @@ var execCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if selector != "" {
+			runSelectedExec(selectConnections(connections, selector), strings.Join(args, " "))
+			return
+		}
+		if len(args) < 2 {
+			_ = cmd.Help()
+			os.Exit(1)
+		}
+		connectionName := args[0]
+		command := strings.Join(args[1:], " ")
+
 		var conn *config.Connection
 		for i, c := range connections {
 			if c.Name == connectionName {
@@ -43,3 +53,31 @@ var execCmd = &cobra.Command{
 		}
 	},
 }
+
+// runSelectedExec runs a command on each of the connections in turn and prints a
+// summary. It exits with an error status if the command failed on any host.
+func runSelectedExec(conns []*config.Connection, command string) {
+	var failed []string
+	for i, conn := range conns {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(i18n.TWith("exec.host.header", map[string]interface{}{"Name": conn.Name}))
+		if err := ssh.ExecuteRemoteCommand(conn, command); err != nil {
+			failed = append(failed, conn.Name)
+		}
+	}
+	fmt.Println()
+	fmt.Println(i18n.TWith("exec.summary", map[string]interface{}{
+		"Succeeded": len(conns) - len(failed),
+		"Failed":    len(failed),
+	}))
+	if len(failed) > 0 {
+		fmt.Println("  " + strings.Join(failed, ", "))
+		os.Exit(1)
+	}
+}
+
+func init() {
+	execCmd.Flags().StringP("selector", "s", "", i18n.T("exec.flag.selector"))
+}
@@ -6,12 +6,21 @@ import (
 	"github.com/spf13/cobra"
 	"gossh/internal/config"
 	"gossh/internal/i18n"
+	"io"
 	"os"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
 )
 
+// ExportData is the content of an export. Connections are exported as saved, together
+// with the defaults they inherit from.
 type ExportData struct {
-	Connections []config.Connection `json:"connections"`
-	Credentials []config.Credential `json:"credentials"`
+	Defaults    config.Defaults            `json:"defaults,omitzero"`
+	Groups      map[string]config.Defaults `json:"groups,omitempty"`
+	Connections []config.Connection        `json:"connections"`
+	Credentials []config.Credential        `json:"credentials"`
 }
 
 var exportCmd = &cobra.Command{
@@ -19,26 +28,81 @@ var exportCmd = &cobra.Command{
 	Short: i18n.T("export.short"),
 	Long:  i18n.T("export.long"),
 	Run: func(cmd *cobra.Command, args []string) {
-		connections, err := config.LoadConnections()
+		format, _ := cmd.Flags().GetString("format")
+		plaintext, _ := cmd.Flags().GetBool("plaintext")
+		group, _ := cmd.Flags().GetString("group")
+		names, _ := cmd.Flags().GetStringSlice("name")
+		selector, _ := cmd.Flags().GetString("selector")
+		noCredentials, _ := cmd.Flags().GetBool("no-credentials")
+		if format != "json" && format != "ssh-config" && format != "csv" {
+			fmt.Println(i18n.TWith("export.error.invalid.format", map[string]interface{}{"Format": format}))
+			os.Exit(1)
+		}
+
+		doc, err := config.LoadDocument()
 		if err != nil {
 			if !os.IsNotExist(err) {
 				fmt.Println(i18n.TWith("export.error.loading.connections", map[string]interface{}{"Error": err}))
 				os.Exit(1)
 			}
+			doc = &config.Document{}
 		}
 
-		credentials, err := config.LoadCredentials()
-		if err != nil {
-			if !os.IsNotExist(err) {
-				fmt.Println(i18n.TWith("export.error.loading.credentials", map[string]interface{}{"Error": err}))
+		connections := doc.Connections
+		filtered := group != "" || len(names) > 0 || selector != ""
+		if filtered {
+			connections = filterExport(connections, group, names, selector)
+		}
+		// ssh, spreadsheets and the credential filter need the settings inherited from
+		// the defaults, as they cannot carry the defaults themselves.
+		resolved := make([]config.Connection, len(connections))
+		for i, c := range connections {
+			resolved[i] = doc.Resolve(c).Connection
+		}
+
+		if format == "ssh-config" {
+			writeSSHConfig(os.Stdout, resolved)
+			return
+		}
+		if format == "csv" {
+			if err := config.WriteConnectionsCSV(os.Stdout, resolved); err != nil {
+				fmt.Println(i18n.TWith("export.error.writing.csv", map[string]interface{}{"Error": err}))
 				os.Exit(1)
 			}
+			return
+		}
+
+		var credentials []config.Credential
+		if !noCredentials {
+			credentials, err = config.LoadCredentials()
+			if err != nil {
+				if !os.IsNotExist(err) {
+					fmt.Println(i18n.TWith("export.error.loading.credentials", map[string]interface{}{"Error": err}))
+					os.Exit(1)
+				}
+			}
+			if filtered {
+				credentials = referencedCredentials(credentials, resolved)
+			}
 		}
 
 		exportData := ExportData{
+			Defaults:    doc.Defaults,
+			Groups:      doc.Groups,
 			Connections: connections,
 			Credentials: credentials,
 		}
+		if filtered {
+			exportData.Groups = nil
+			for _, c := range connections {
+				if settings, ok := doc.Groups[c.Group]; ok && c.Group != "" {
+					if exportData.Groups == nil {
+						exportData.Groups = make(map[string]config.Defaults)
+					}
+					exportData.Groups[c.Group] = settings
+				}
+			}
+		}
 
 		jsonData, err := json.MarshalIndent(exportData, "", "  ")
 		if err != nil {
@@ -46,10 +110,161 @@ var exportCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if !plaintext {
+			passphrase := readPassphrase(cmd, true)
+			jsonData, err = config.SealBundle(jsonData, passphrase)
+			if err != nil {
+				fmt.Println(i18n.TWith("export.error.encrypting", map[string]interface{}{"Error": err}))
+				os.Exit(1)
+			}
+		}
+
 		fmt.Println(string(jsonData))
 	},
 }
 
+// readPassphrase returns the passphrase of an encrypted export, read from the file
+// given with --passphrase-file or else asked for on the terminal, twice if confirm is
+// set. Prompts and errors go to standard error, as the export itself goes to standard
+// output.
+func readPassphrase(cmd *cobra.Command, confirm bool) string {
+	if path, _ := cmd.Flags().GetString("passphrase-file"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.TWith("passphrase.error.reading", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+		passphrase, _, _ := strings.Cut(string(data), "\n")
+		passphrase = strings.TrimSuffix(passphrase, "\r")
+		if passphrase == "" {
+			fmt.Fprintln(os.Stderr, i18n.T("passphrase.error.empty"))
+			os.Exit(1)
+		}
+		return passphrase
+	}
+
+	prompt := func(id string) string {
+		fmt.Fprint(os.Stderr, i18n.T(id))
+		bytePassphrase, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.TWith("passphrase.error.reading", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+		return string(bytePassphrase)
+	}
+	passphrase := prompt("passphrase.prompt")
+	if passphrase == "" {
+		fmt.Fprintln(os.Stderr, i18n.T("passphrase.error.empty"))
+		os.Exit(1)
+	}
+	if confirm && prompt("passphrase.confirm") != passphrase {
+		fmt.Fprintln(os.Stderr, i18n.T("passphrase.error.mismatch"))
+		os.Exit(1)
+	}
+	return passphrase
+}
+
+// filterExport returns the connections that are in group, are named in names and match
+// selector, leaving out each filter that is not set. It exits with an error if a name
+// is unknown or nothing matches.
+func filterExport(connections []config.Connection, group string, names []string, selector string) []config.Connection {
+	var sel config.Selector
+	if selector != "" {
+		var err error
+		if sel, err = config.ParseSelector(selector); err != nil {
+			fmt.Println(i18n.TWith("selector.error.invalid", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+	}
+	for _, name := range names {
+		if findConnection(connections, name) == nil {
+			fmt.Println(i18n.TWith("error.connection.not.found", map[string]interface{}{"Name": name}))
+			os.Exit(1)
+		}
+	}
+
+	var result []config.Connection
+	for _, c := range connections {
+		if group != "" && c.Group != group {
+			continue
+		}
+		if len(names) > 0 && !slices.Contains(names, c.Name) {
+			continue
+		}
+		if selector != "" && !sel.Matches(c) {
+			continue
+		}
+		result = append(result, c)
+	}
+	if len(result) == 0 {
+		fmt.Println(i18n.T("export.error.none"))
+		os.Exit(1)
+	}
+	return result
+}
+
+// referencedCredentials returns the credentials whose alias one of connections uses.
+func referencedCredentials(credentials []config.Credential, connections []config.Connection) []config.Credential {
+	used := make(map[string]bool)
+	for _, c := range connections {
+		if c.CredentialAlias != "" {
+			used[c.CredentialAlias] = true
+		}
+	}
+	var result []config.Credential
+	for _, cred := range credentials {
+		if used[cred.Alias] {
+			result = append(result, cred)
+		}
+	}
+	return result
+}
+
+// writeSSHConfig writes a Host block in OpenSSH client configuration format for each
+// connection. Connections using a saved password are marked with a comment, since ssh
+// cannot read gossh's credentials.
+func writeSSHConfig(w io.Writer, connections []config.Connection) {
+	fmt.Fprintf(w, "# %s\n", i18n.TWith("export.ssh.header", map[string]interface{}{"Count": len(connections)}))
+	for _, c := range connections {
+		fmt.Fprintln(w)
+		if c.Group != "" {
+			fmt.Fprintf(w, "# %s\n", i18n.TWith("export.ssh.group", map[string]interface{}{"Group": c.Group}))
+		}
+		if c.CredentialAlias != "" {
+			fmt.Fprintf(w, "# %s\n", i18n.TWith("export.ssh.password", map[string]interface{}{"Alias": c.CredentialAlias}))
+		}
+		fmt.Fprintf(w, "Host %s\n", sshConfigQuote(c.Name))
+		fmt.Fprintf(w, "    HostName %s\n", c.Host)
+		fmt.Fprintf(w, "    User %s\n", sshConfigQuote(c.User))
+		if c.Port != 0 && c.Port != 22 {
+			fmt.Fprintf(w, "    Port %d\n", c.Port)
+		}
+		if c.KeyPath != "" {
+			fmt.Fprintf(w, "    IdentityFile %s\n", sshConfigQuote(c.KeyPath))
+			fmt.Fprintln(w, "    IdentitiesOnly yes")
+		}
+		if c.ProxyJump != "" {
+			fmt.Fprintf(w, "    ProxyJump %s\n", c.ProxyJump)
+		}
+	}
+}
+
+// sshConfigQuote double-quotes a value containing whitespace.
+func sshConfigQuote(value string) string {
+	if strings.ContainsAny(value, " \t") {
+		return `"` + value + `"`
+	}
+	return value
+}
+
 func init() {
+	exportCmd.Flags().String("format", "json", i18n.T("export.flag.format"))
+	exportCmd.Flags().StringP("group", "g", "", i18n.T("export.flag.group"))
+	exportCmd.Flags().StringSliceP("name", "n", nil, i18n.T("export.flag.name"))
+	exportCmd.Flags().StringP("selector", "s", "", i18n.T("export.flag.selector"))
+	exportCmd.Flags().Bool("no-credentials", false, i18n.T("export.flag.no.credentials"))
+	exportCmd.Flags().Bool("plaintext", false, i18n.T("export.flag.plaintext"))
+	exportCmd.Flags().String("passphrase-file", "", i18n.T("flag.passphrase.file"))
 	configCmd.AddCommand(exportCmd)
 }
@@ -2,12 +2,15 @@ package cmd
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"github.com/spf13/cobra"
 	"gossh/internal/config"
 	"gossh/internal/i18n"
+	"gossh/internal/ssh"
 	"os"
+	"slices"
 	"strings"
 )
 
@@ -19,6 +22,21 @@ var importCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		filePath := args[0]
 		force, _ := cmd.Flags().GetBool("force")
+		merge, _ := cmd.Flags().GetBool("merge")
+		strategy, _ := cmd.Flags().GetString("strategy")
+		format, _ := cmd.Flags().GetString("format")
+		if format != "json" && format != "csv" {
+			fmt.Println(i18n.TWith("import.error.invalid.format", map[string]interface{}{"Format": format}))
+			os.Exit(1)
+		}
+		if !slices.Contains(mergeStrategies, strategy) {
+			fmt.Println(i18n.TWith("import.error.invalid.strategy", map[string]interface{}{"Strategy": strategy}))
+			os.Exit(1)
+		}
+		if cmd.Flags().Changed("strategy") && !merge {
+			fmt.Println(i18n.T("import.error.strategy.without.merge"))
+			os.Exit(1)
+		}
 
 		fileData, err := os.ReadFile(filePath)
 		if err != nil {
@@ -27,9 +45,29 @@ var importCmd = &cobra.Command{
 		}
 
 		var importData ExportData
-		if err := json.Unmarshal(fileData, &importData); err != nil {
-			fmt.Println(i18n.TWith("import.error.parsing.json", map[string]interface{}{"Error": err}))
-			os.Exit(1)
+		invalidRows := 0
+		if format == "csv" {
+			importData.Connections, invalidRows = readCSVImport(fileData)
+		} else {
+			if config.IsBundle(fileData) {
+				fileData, err = config.OpenBundle(fileData, readPassphrase(cmd, false))
+				if err != nil {
+					fmt.Println(i18n.TWith("import.error.decrypting", map[string]interface{}{"File": filePath, "Error": err}))
+					os.Exit(1)
+				}
+			}
+			if err := json.Unmarshal(fileData, &importData); err != nil {
+				fmt.Println(i18n.TWith("import.error.parsing.json", map[string]interface{}{"Error": err}))
+				os.Exit(1)
+			}
+		}
+
+		if merge {
+			runMergeImport(importData, strategy, force)
+			if invalidRows > 0 {
+				os.Exit(1)
+			}
+			return
 		}
 
 		if !force {
@@ -50,21 +88,98 @@ var importCmd = &cobra.Command{
 			}
 		}
 
-		if err := config.SaveConnections(importData.Connections); err != nil {
+		// A spreadsheet holds no defaults, so the saved ones are kept.
+		err = config.ModifyDocument(func(doc *config.Document) error {
+			if format != "csv" {
+				doc.Defaults, doc.Groups = importData.Defaults, importData.Groups
+			}
+			doc.Connections = importData.Connections
+			return nil
+		})
+		if err != nil {
 			fmt.Println(i18n.TWith("import.error.saving.connections", map[string]interface{}{"Error": err}))
 			os.Exit(1)
 		}
 
-		if err := config.SaveCredentials(importData.Credentials); err != nil {
-			fmt.Println(i18n.TWith("import.error.saving.credentials", map[string]interface{}{"Error": err}))
-			os.Exit(1)
+		// A spreadsheet holds no passwords, so the saved ones are kept.
+		if format != "csv" {
+			if err := config.SaveCredentials(importData.Credentials); err != nil {
+				fmt.Println(i18n.TWith("import.error.saving.credentials", map[string]interface{}{"Error": err}))
+				os.Exit(1)
+			}
 		}
 
 		fmt.Println(i18n.T("import.success"))
+		if invalidRows > 0 {
+			os.Exit(1)
+		}
 	},
 }
 
+// readCSVImport reads the connections of a CSV file and checks them against the
+// configuration, printing an error with the line number of every invalid row. It
+// returns the valid connections and the number of rows left out.
+func readCSVImport(data []byte) ([]config.Connection, int) {
+	rows, err := config.ReadConnectionsCSV(bytes.NewReader(data))
+	if err != nil {
+		fmt.Println(i18n.TWith("import.error.parsing.csv", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+	doc, err := config.LoadDocument()
+	if err != nil {
+		fmt.Println(i18n.TWith("error.loading.connections", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+
+	var connections []config.Connection
+	invalid := 0
+	for _, row := range rows {
+		err := row.Err
+		if err == nil {
+			err = checkCSVConnection(doc, row.Connection)
+		}
+		if err != nil {
+			fmt.Println(i18n.TWith("import.csv.error.line", map[string]interface{}{"Line": row.Line, "Error": err}))
+			invalid++
+			continue
+		}
+		connections = append(connections, row.Connection)
+	}
+	if invalid > 0 {
+		fmt.Println(i18n.TWith("import.csv.skipped", map[string]interface{}{"Count": invalid}))
+	}
+	if len(connections) == 0 {
+		fmt.Println(i18n.T("import.csv.none"))
+		os.Exit(1)
+	}
+	return connections, invalid
+}
+
+// checkCSVConnection checks the settings of an imported connection that depend on the
+// rest of the configuration or on the ssh package.
+func checkCSVConnection(doc *config.Document, conn config.Connection) error {
+	if conn.CredentialAlias != "" && !credentialAliasExists(conn.CredentialAlias) {
+		return i18n.Error("import.csv.error.credential", map[string]interface{}{"Alias": conn.CredentialAlias})
+	}
+	if doc.Resolve(conn).User == "" {
+		return i18n.Error("import.csv.error.user", nil)
+	}
+	if conn.BandwidthLimit != "" {
+		if _, err := ssh.ParseBandwidth(conn.BandwidthLimit); err != nil {
+			return i18n.Error("import.csv.error.limit", map[string]interface{}{"Limit": conn.BandwidthLimit})
+		}
+	}
+	if _, _, err := ssh.ParseControlPersist(conn.ControlPersist); err != nil {
+		return err
+	}
+	return nil
+}
+
 func init() {
 	importCmd.Flags().BoolP("force", "f", false, i18n.T("import.flag.force"))
+	importCmd.Flags().BoolP("merge", "m", false, i18n.T("import.flag.merge"))
+	importCmd.Flags().String("strategy", strategySkip, i18n.T("import.flag.strategy"))
+	importCmd.Flags().String("passphrase-file", "", i18n.T("flag.passphrase.file"))
+	importCmd.Flags().String("format", "json", i18n.T("import.flag.format"))
 	configCmd.AddCommand(importCmd)
 }
@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// inventoryLabel is the label marking connections imported from an Ansible inventory;
+// its value is the inventory's absolute path, so that inventories with the same file
+// name in different directories are told apart. --sync only updates and removes
+// connections carrying the label of the inventory being synced.
+const inventoryLabel = "inventory"
+
+var importAnsibleCmd = &cobra.Command{
+	Use:   "import-ansible <inventory>",
+	Short: i18n.T("import.ansible.short"),
+	Long:  i18n.T("import.ansible.long"),
+	Args:  cobra.ExactArgs(1),
+	Run:   runImportAnsible,
+}
+
+func init() {
+	importAnsibleCmd.Flags().String("groups-as", "group", i18n.T("import.ansible.flag.groups.as"))
+	importAnsibleCmd.Flags().Bool("sync", false, i18n.T("import.ansible.flag.sync"))
+	importAnsibleCmd.Flags().Bool("dry-run", false, i18n.T("import.ansible.flag.dry.run"))
+	importAnsibleCmd.Flags().BoolP("yes", "y", false, i18n.T("import.ansible.flag.yes"))
+	configCmd.AddCommand(importAnsibleCmd)
+}
+
+// ansibleChange is what importing does with one connection.
+type ansibleChange struct {
+	conn config.Connection
+	diff []string
+	kind string // add, update, remove, unchanged or exists
+}
+
+func runImportAnsible(cmd *cobra.Command, args []string) {
+	path := args[0]
+	groupsAs, _ := cmd.Flags().GetString("groups-as")
+	sync, _ := cmd.Flags().GetBool("sync")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	yes, _ := cmd.Flags().GetBool("yes")
+	if groupsAs != "group" && groupsAs != "tags" {
+		fmt.Println(i18n.TWith("import.ansible.error.groups.as", map[string]interface{}{"Value": groupsAs}))
+		os.Exit(1)
+	}
+
+	hosts, err := config.ParseAnsibleInventory(path)
+	if err != nil {
+		fmt.Println(i18n.TWith("import.error.reading.file", map[string]interface{}{"File": path, "Error": err}))
+		os.Exit(1)
+	}
+	if len(hosts) == 0 && !sync {
+		fmt.Println(i18n.TWith("import.ssh.none", map[string]interface{}{"File": path}))
+		return
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		fmt.Println(i18n.TWith("import.error.reading.file", map[string]interface{}{"File": path, "Error": err}))
+		os.Exit(1)
+	}
+	source := inventoryLabel + "=" + abs
+	if err := config.ValidateTag(source); err != nil {
+		fmt.Println(i18n.TWith("tag.error.invalid", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+
+	// Group names of the inventory become tags; they are the tags --sync manages.
+	managed := map[string]bool{source: true}
+	var found []config.Connection
+	for _, h := range hosts {
+		conn, err := h.Connection()
+		if err != nil {
+			fmt.Println(i18n.TWith("import.error.reading.file", map[string]interface{}{"File": path, "Error": err}))
+			os.Exit(1)
+		}
+		groups := h.Groups
+		if groupsAs == "group" && len(groups) > 0 {
+			conn.Group, groups = groups[0], groups[1:]
+		}
+		conn.Tags = []string{source}
+		for _, g := range h.Groups {
+			if config.ValidateTag(g) == nil {
+				managed[g] = true
+			}
+		}
+		for _, g := range groups {
+			if config.ValidateTag(g) == nil {
+				conn.Tags = append(conn.Tags, g)
+			}
+		}
+		found = append(found, conn)
+	}
+
+	doc, err := config.LoadDocument()
+	if err != nil {
+		fmt.Println(i18n.TWith("error.loading.connections", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+	// Like ssh, hosts that get no user from the inventory or the defaults use the
+	// local one.
+	if u, err := user.Current(); err == nil {
+		for i, conn := range found {
+			if doc.Resolve(conn).User == "" {
+				found[i].User = u.Username
+			}
+		}
+	}
+	changes := planAnsibleImport(doc, found, source, managed, groupsAs, sync)
+
+	fmt.Println(i18n.TWith("import.ssh.found", map[string]interface{}{"Count": len(hosts), "File": path}))
+	pending := 0
+	unchanged := 0
+	for _, c := range changes {
+		switch c.kind {
+		case "add":
+			r := doc.Resolve(c.conn)
+			fmt.Printf("  + %s  %s@%s:%d\n", r.Name, r.User, r.Host, r.Port)
+		case "update":
+			fmt.Printf("  ~ %s\n", c.conn.Name)
+			for _, d := range c.diff {
+				fmt.Printf("      %s\n", d)
+			}
+		case "remove":
+			fmt.Printf("  - %s\n", c.conn.Name)
+		case "exists":
+			fmt.Printf("  ! %s  %s\n", c.conn.Name, i18n.T("import.ansible.exists"))
+			continue
+		default:
+			unchanged++
+			continue
+		}
+		pending++
+	}
+	if unchanged > 0 {
+		fmt.Println(i18n.TWith("import.ansible.unchanged", map[string]interface{}{"Count": unchanged}))
+	}
+	if pending == 0 {
+		fmt.Println(i18n.T("import.merge.nothing"))
+		return
+	}
+	if dryRun {
+		return
+	}
+	if !yes {
+		fmt.Print(i18n.T("import.confirm.prompt"))
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Println(i18n.T("import.cancelled"))
+			return
+		}
+	}
+
+	added, updated, removed := 0, 0, 0
+	err = config.ModifyConnections(func(connections []config.Connection) ([]config.Connection, error) {
+		// Apply the changes to the current file, which another gossh process may have
+		// changed since it was read.
+		for _, c := range changes {
+			i := slices.IndexFunc(connections, func(conn config.Connection) bool { return conn.Name == c.conn.Name })
+			switch {
+			case c.kind == "add" && i < 0:
+				connections = append(connections, c.conn)
+				added++
+			case c.kind == "add":
+				fmt.Println(i18n.TWith("import.ssh.skipped.exists", map[string]interface{}{"Name": c.conn.Name}))
+			case c.kind == "update" && i >= 0:
+				connections[i] = c.conn
+				updated++
+			case c.kind == "remove" && i >= 0:
+				connections = slices.Delete(connections, i, i+1)
+				removed++
+			}
+		}
+		return connections, nil
+	})
+	if err != nil {
+		fmt.Println(i18n.TWith("import.error.saving.connections", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+	fmt.Println(i18n.TWith("import.ansible.success", map[string]interface{}{
+		"Added":   added,
+		"Updated": updated,
+		"Removed": removed,
+	}))
+}
+
+// planAnsibleImport compares the connections found in an inventory with the saved
+// ones. New hosts are added. With sync, connections imported from the same inventory
+// before are updated to match it, keeping the settings and tags the inventory does not
+// manage, and removed when their host is gone. Settings are compared as they resolve,
+// so a connection is not updated only to inherit a value it already has.
+func planAnsibleImport(doc *config.Document, found []config.Connection, source string, managed map[string]bool, groupsAs string, sync bool) []ansibleChange {
+	saved := doc.Connections
+	byName := make(map[string]config.Connection)
+	for _, c := range saved {
+		byName[c.Name] = c
+	}
+	inInventory := make(map[string]bool)
+
+	var changes []ansibleChange
+	for _, conn := range found {
+		inInventory[conn.Name] = true
+		old, exists := byName[conn.Name]
+		if !exists {
+			changes = append(changes, ansibleChange{conn: conn, kind: "add"})
+			continue
+		}
+		if !sync || !slices.Contains(old.Tags, source) {
+			changes = append(changes, ansibleChange{conn: old, kind: "exists"})
+			continue
+		}
+
+		updated := old
+		updated.Host, updated.User, updated.Port, updated.KeyPath = conn.Host, conn.User, conn.Port, conn.KeyPath
+		if conn.KeyPath != "" {
+			updated.CredentialAlias = ""
+		}
+		if groupsAs == "group" {
+			updated.Group = conn.Group
+		}
+		// Keep the order of the existing tags, so that only real changes show up.
+		var tags []string
+		for _, t := range old.Tags {
+			if !managed[t] || slices.Contains(conn.Tags, t) {
+				tags = append(tags, t)
+			}
+		}
+		for _, t := range conn.Tags {
+			if !slices.Contains(tags, t) {
+				tags = append(tags, t)
+			}
+		}
+		updated.Tags = tags
+
+		if diff := connectionDiff(doc.Resolve(old).Connection, doc.Resolve(updated).Connection); len(diff) > 0 {
+			changes = append(changes, ansibleChange{conn: updated, diff: diff, kind: "update"})
+		} else {
+			changes = append(changes, ansibleChange{conn: old, kind: "unchanged"})
+		}
+	}
+
+	if sync {
+		for _, c := range saved {
+			if !inInventory[c.Name] && slices.Contains(c.Tags, source) {
+				changes = append(changes, ansibleChange{conn: c, kind: "remove"})
+			}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,361 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Strategies for imported entries that exist locally with different settings.
+const (
+	strategySkip      = "skip"
+	strategyOverwrite = "overwrite"
+	strategyRename    = "rename"
+	strategyAsk       = "ask"
+)
+
+// mergeStrategies lists the values accepted by import --strategy.
+var mergeStrategies = []string{strategySkip, strategyOverwrite, strategyRename, strategyAsk}
+
+// importChange describes what merging does with one imported connection or
+// credential.
+type importChange struct {
+	Name    string   // name or alias in the imported file
+	NewName string   // name it is saved under, which differs from Name when renamed
+	Diff    []string // settings that differ from the local entry of the same name
+	Exists  bool     // an entry with the same name exists locally
+	Action  string   // a strategy for conflicts, "" for new and unchanged entries
+}
+
+// Conflict reports whether the entry exists locally with different settings.
+func (c importChange) Conflict() bool {
+	return c.Exists && len(c.Diff) > 0
+}
+
+// Imported reports whether the entry is written to the local configuration.
+func (c importChange) Imported() bool {
+	if !c.Exists {
+		return true
+	}
+	return c.Conflict() && (c.Action == strategyOverwrite || c.Action == strategyRename)
+}
+
+// runMergeImport adds the connections and credentials of importData to the local
+// configuration, leaving local entries that are not in the file alone.
+func runMergeImport(importData ExportData, strategy string, force bool) {
+	doc, err := config.LoadDocument()
+	if err != nil {
+		fmt.Println(i18n.TWith("error.loading.connections", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+	credentials, err := config.LoadCredentials()
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Println(i18n.TWith("export.error.loading.credentials", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+
+	// The local defaults are kept. Imported connections keep the settings they inherit
+	// from the defaults in the file by setting them themselves where needed.
+	exported := &config.Document{Defaults: importData.Defaults, Groups: importData.Groups}
+	for i, c := range importData.Connections {
+		importData.Connections[i] = doc.Rebase(c, exported)
+	}
+
+	// Both sides are compared as they resolve in the local configuration, so that a
+	// setting inherited on one side and set on the other is not a conflict.
+	localConns := make(map[string]config.Connection)
+	for _, c := range doc.Connections {
+		localConns[c.Name] = doc.Resolve(c).Connection
+	}
+	connChanges := make([]importChange, len(importData.Connections))
+	for i, c := range importData.Connections {
+		change := importChange{Name: c.Name, NewName: c.Name, Action: strategy}
+		if local, ok := localConns[c.Name]; ok {
+			change.Exists = true
+			change.Diff = connectionDiff(local, doc.Resolve(c).Connection)
+		}
+		connChanges[i] = change
+	}
+
+	localCreds := make(map[string]config.Credential)
+	for _, c := range credentials {
+		localCreds[c.Alias] = c
+	}
+	credChanges := make([]importChange, len(importData.Credentials))
+	for i, c := range importData.Credentials {
+		change := importChange{Name: c.Alias, NewName: c.Alias, Action: strategy}
+		if local, ok := localCreds[c.Alias]; ok {
+			change.Exists = true
+			if local.Password != c.Password {
+				change.Diff = []string{i18n.T("import.merge.password.differs")}
+			}
+		}
+		credChanges[i] = change
+	}
+
+	if strategy != strategyAsk {
+		renameConflicts(connChanges, localConns)
+		renameConflicts(credChanges, localCreds)
+	}
+	fmt.Println(i18n.T("import.merge.connections"))
+	printImportChanges(connChanges)
+	fmt.Println(i18n.T("import.merge.credentials"))
+	printImportChanges(credChanges)
+
+	reader := bufio.NewReader(os.Stdin)
+	if strategy == strategyAsk {
+		askConflicts(reader, connChanges)
+		askConflicts(reader, credChanges)
+		renameConflicts(connChanges, localConns)
+		renameConflicts(credChanges, localCreds)
+	}
+
+	if countImported(connChanges) == 0 && countImported(credChanges) == 0 {
+		fmt.Println(i18n.T("import.merge.nothing"))
+		return
+	}
+	if !force {
+		fmt.Print(i18n.T("import.confirm.prompt"))
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Println(i18n.T("import.cancelled"))
+			os.Exit(0)
+		}
+	}
+
+	// Connections renamed on import keep pointing at each other and at renamed
+	// credentials.
+	connNames := renamedEntries(connChanges)
+	credAliases := renamedEntries(credChanges)
+	imported := make([]config.Connection, len(importData.Connections))
+	for i, c := range importData.Connections {
+		c.Name = connChanges[i].NewName
+		for oldName, newName := range connNames {
+			c.ProxyJump = config.RenameJumpHost(c.ProxyJump, oldName, newName)
+		}
+		if newAlias, ok := credAliases[c.CredentialAlias]; ok {
+			c.CredentialAlias = newAlias
+		}
+		imported[i] = c
+	}
+
+	// Credentials go first, so that no connection refers to one that is not saved yet.
+	savedCreds := 0
+	err = config.ModifyCredentials(func(credentials []config.Credential) ([]config.Credential, error) {
+		index := make(map[string]int)
+		for i, c := range credentials {
+			index[c.Alias] = i
+		}
+		for i, cred := range importData.Credentials {
+			change := credChanges[i]
+			if !change.Imported() {
+				continue
+			}
+			cred.Alias = change.NewName
+			j, exists := index[cred.Alias]
+			switch {
+			case exists && change.Action == strategyOverwrite:
+				credentials[j] = cred
+			case exists:
+				// Added by another gossh process since the preview was shown.
+				fmt.Println(i18n.TWith("import.merge.skipped.exists", map[string]interface{}{"Name": cred.Alias}))
+				continue
+			default:
+				index[cred.Alias] = len(credentials)
+				credentials = append(credentials, cred)
+			}
+			savedCreds++
+		}
+		return credentials, nil
+	})
+	if err != nil {
+		fmt.Println(i18n.TWith("import.error.saving.credentials", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+
+	savedConns := 0
+	err = config.ModifyConnections(func(connections []config.Connection) ([]config.Connection, error) {
+		index := make(map[string]int)
+		for i, c := range connections {
+			index[c.Name] = i
+		}
+		for i, conn := range imported {
+			change := connChanges[i]
+			if !change.Imported() {
+				continue
+			}
+			j, exists := index[conn.Name]
+			switch {
+			case exists && change.Action == strategyOverwrite:
+				connections[j] = conn
+			case exists:
+				fmt.Println(i18n.TWith("import.merge.skipped.exists", map[string]interface{}{"Name": conn.Name}))
+				continue
+			default:
+				index[conn.Name] = len(connections)
+				connections = append(connections, conn)
+			}
+			savedConns++
+		}
+		return connections, nil
+	})
+	if err != nil {
+		fmt.Println(i18n.TWith("import.error.saving.connections", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+
+	fmt.Println(i18n.TWith("import.merge.success", map[string]interface{}{
+		"Connections": savedConns,
+		"Credentials": savedCreds,
+	}))
+}
+
+// connectionDiff lists the settings of imported that differ from local, as
+// "setting: old -> new".
+func connectionDiff(local, imported config.Connection) []string {
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"group", local.Group, imported.Group},
+		{"user", local.User, imported.User},
+		{"host", local.Host, imported.Host},
+		{"port", portString(local.Port), portString(imported.Port)},
+		{"key_path", local.KeyPath, imported.KeyPath},
+		{"credential_alias", local.CredentialAlias, imported.CredentialAlias},
+		{"bandwidth_limit", local.BandwidthLimit, imported.BandwidthLimit},
+		{"control_persist", local.ControlPersist, imported.ControlPersist},
+		{"proxy_jump", local.ProxyJump, imported.ProxyJump},
+		{"tags", strings.Join(local.Tags, ","), strings.Join(imported.Tags, ",")},
+	}
+	var diff []string
+	for _, f := range fields {
+		if f.old != f.new {
+			diff = append(diff, fmt.Sprintf("%s: %q -> %q", f.name, f.old, f.new))
+		}
+	}
+	return diff
+}
+
+func portString(port int) string {
+	if port == 0 {
+		return ""
+	}
+	return strconv.Itoa(port)
+}
+
+// renameConflicts picks a free name for each conflicting entry that is imported
+// under another name: <name>-imported, then <name>-imported-2 and so on.
+func renameConflicts[T any](changes []importChange, local map[string]T) {
+	taken := make(map[string]bool)
+	for name := range local {
+		taken[name] = true
+	}
+	for _, c := range changes {
+		taken[c.Name] = true
+	}
+	for i, c := range changes {
+		if !c.Conflict() || c.Action != strategyRename || c.NewName != c.Name {
+			continue
+		}
+		newName := c.Name + "-imported"
+		for n := 2; taken[newName]; n++ {
+			newName = fmt.Sprintf("%s-imported-%d", c.Name, n)
+		}
+		taken[newName] = true
+		changes[i].NewName = newName
+	}
+}
+
+// askConflicts asks how to resolve each conflicting entry.
+func askConflicts(reader *bufio.Reader, changes []importChange) {
+	for i, c := range changes {
+		if !c.Conflict() {
+			continue
+		}
+		for {
+			fmt.Print(i18n.TWith("import.merge.ask.prompt", map[string]interface{}{"Name": c.Name}))
+			response, err := reader.ReadString('\n')
+			response = strings.TrimSpace(strings.ToLower(response))
+			if response == "" && err != nil {
+				fmt.Println()
+				fmt.Println(i18n.T("import.cancelled"))
+				os.Exit(0)
+			}
+			action := ""
+			for _, s := range []string{strategySkip, strategyOverwrite, strategyRename} {
+				if response == s || response == s[:1] {
+					action = s
+				}
+			}
+			if action != "" {
+				changes[i].Action = action
+				break
+			}
+		}
+	}
+}
+
+// printImportChanges prints a line for each new or conflicting entry, followed by a
+// summary line.
+func printImportChanges(changes []importChange) {
+	added, conflicting, unchanged := 0, 0, 0
+	for _, c := range changes {
+		switch {
+		case !c.Exists:
+			added++
+			fmt.Printf("  + %s\n", c.Name)
+		case c.Conflict():
+			conflicting++
+			fmt.Printf("  ~ %s  [%s]\n", c.Name, describeMergeAction(c))
+			for _, d := range c.Diff {
+				fmt.Printf("      %s\n", d)
+			}
+		default:
+			unchanged++
+		}
+	}
+	fmt.Println(i18n.TWith("import.merge.summary", map[string]interface{}{
+		"Added":       added,
+		"Conflicting": conflicting,
+		"Unchanged":   unchanged,
+	}))
+}
+
+func describeMergeAction(c importChange) string {
+	switch c.Action {
+	case strategyOverwrite:
+		return i18n.T("import.merge.action.overwrite")
+	case strategyRename:
+		return i18n.TWith("import.merge.action.rename", map[string]interface{}{"Name": c.NewName})
+	case strategyAsk:
+		return i18n.T("import.merge.action.ask")
+	}
+	return i18n.T("import.merge.action.skip")
+}
+
+func countImported(changes []importChange) int {
+	n := 0
+	for _, c := range changes {
+		if c.Imported() {
+			n++
+		}
+	}
+	return n
+}
+
+// renamedEntries maps the original names of the renamed entries to their new names.
+func renamedEntries(changes []importChange) map[string]string {
+	renamed := make(map[string]string)
+	for _, c := range changes {
+		if c.Imported() && c.NewName != c.Name {
+			renamed[c.Name] = c.NewName
+		}
+	}
+	return renamed
+}
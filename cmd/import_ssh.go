@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var importSSHCmd = &cobra.Command{
+	Use:   "import-ssh [path]",
+	Short: i18n.T("import.ssh.short"),
+	Long:  i18n.T("import.ssh.long"),
+	Args:  cobra.MaximumNArgs(1),
+	Run:   runImportSSH,
+}
+
+func init() {
+	importSSHCmd.Flags().StringP("group", "g", "", i18n.T("import.ssh.flag.group"))
+	importSSHCmd.Flags().Bool("dry-run", false, i18n.T("import.ssh.flag.dry.run"))
+	importSSHCmd.Flags().BoolP("yes", "y", false, i18n.T("import.ssh.flag.yes"))
+	configCmd.AddCommand(importSSHCmd)
+}
+
+func runImportSSH(cmd *cobra.Command, args []string) {
+	group, _ := cmd.Flags().GetString("group")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	path := ""
+	if len(args) == 1 {
+		path = args[0]
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Println(i18n.TWith("import.error.reading.file", map[string]interface{}{"File": "~/.ssh/config", "Error": err}))
+			os.Exit(1)
+		}
+		path = filepath.Join(home, ".ssh", "config")
+	}
+
+	found, unsupported, err := config.ParseSSHConfig(path)
+	if err != nil {
+		fmt.Println(i18n.TWith("import.error.reading.file", map[string]interface{}{"File": path, "Error": err}))
+		os.Exit(1)
+	}
+	if len(found) == 0 {
+		fmt.Println(i18n.TWith("import.ssh.none", map[string]interface{}{"File": path}))
+		return
+	}
+
+	connections, err := config.LoadConnections()
+	if err != nil {
+		fmt.Println(i18n.TWith("error.loading.connections", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+	exists := make(map[string]bool)
+	for _, c := range connections {
+		exists[c.Name] = true
+	}
+
+	fmt.Println(i18n.TWith("import.ssh.found", map[string]interface{}{"Count": len(found), "File": path}))
+	for i, c := range found {
+		fmt.Printf("%3d. %s\n", i+1, describeImportedConnection(c, exists[c.Name]))
+	}
+	if len(unsupported) > 0 {
+		fmt.Println(i18n.T("import.ssh.unsupported"))
+		for _, d := range unsupported {
+			fmt.Printf("  - %s (%s:%d)\n", d.Name, d.File, d.Line)
+		}
+	}
+	if dryRun {
+		return
+	}
+
+	var selected []int
+	if yes {
+		for i := range found {
+			selected = append(selected, i)
+		}
+	} else {
+		fmt.Print(i18n.T("import.ssh.select.prompt"))
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		selected, err = parseSelection(strings.TrimSpace(response), found)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if len(selected) == 0 {
+			fmt.Println(i18n.T("import.cancelled"))
+			return
+		}
+	}
+
+	imported := 0
+	err = config.ModifyConnections(func(connections []config.Connection) ([]config.Connection, error) {
+		// Re-check against the current file, which another gossh process may have
+		// changed while we were waiting for the selection.
+		exists := make(map[string]bool)
+		for _, c := range connections {
+			exists[c.Name] = true
+		}
+		for _, i := range selected {
+			conn := found[i]
+			if exists[conn.Name] {
+				fmt.Println(i18n.TWith("import.ssh.skipped.exists", map[string]interface{}{"Name": conn.Name}))
+				continue
+			}
+			if group != "" {
+				conn.Group = group
+			}
+			connections = append(connections, conn)
+			exists[conn.Name] = true
+			imported++
+		}
+		return connections, nil
+	})
+	if err != nil {
+		fmt.Println(i18n.TWith("import.error.saving.connections", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+	if imported == 0 {
+		return
+	}
+	fmt.Println(i18n.TWith("import.ssh.success", map[string]interface{}{"Count": imported}))
+}
+
+// describeImportedConnection returns a one-line summary of a connection found in an
+// imported file.
+func describeImportedConnection(c config.Connection, exists bool) string {
+	line := fmt.Sprintf("%s  %s@%s:%d", c.Name, c.User, c.Host, c.Port)
+	if c.KeyPath != "" {
+		line += "  " + i18n.TWith("import.ssh.key", map[string]interface{}{"Path": c.KeyPath})
+	}
+	if c.ProxyJump != "" {
+		line += "  " + i18n.TWith("import.ssh.jump", map[string]interface{}{"Jump": c.ProxyJump})
+	}
+	if exists {
+		line += "  " + i18n.T("import.ssh.exists")
+	}
+	return line
+}
+
+// parseSelection parses a comma-separated list of numbers, ranges such as 2-4, and
+// connection names, or "all", into indexes of found.
+func parseSelection(input string, found []config.Connection) ([]int, error) {
+	if input == "" {
+		return nil, nil
+	}
+	if strings.EqualFold(input, "all") {
+		indexes := make([]int, len(found))
+		for i := range found {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+
+	var indexes []int
+	chosen := make(map[int]bool)
+	add := func(i int) {
+		if !chosen[i] {
+			chosen[i] = true
+			indexes = append(indexes, i)
+		}
+	}
+	for _, item := range strings.Split(input, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if from, to, ok := strings.Cut(item, "-"); ok {
+			start, err1 := strconv.Atoi(strings.TrimSpace(from))
+			end, err2 := strconv.Atoi(strings.TrimSpace(to))
+			if err1 == nil && err2 == nil && start >= 1 && end <= len(found) && start <= end {
+				for i := start; i <= end; i++ {
+					add(i - 1)
+				}
+				continue
+			}
+		}
+		if n, err := strconv.Atoi(item); err == nil {
+			if n < 1 || n > len(found) {
+				return nil, i18n.Error("import.ssh.error.selection", map[string]interface{}{"Item": item})
+			}
+			add(n - 1)
+			continue
+		}
+		match := -1
+		for i, c := range found {
+			if c.Name == item {
+				match = i
+			}
+		}
+		if match < 0 {
+			return nil, i18n.Error("import.ssh.error.selection", map[string]interface{}{"Item": item})
+		}
+		add(match)
+	}
+	return indexes, nil
+}
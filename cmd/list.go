@@ -7,6 +7,7 @@ import (
 	"gossh/internal/i18n"
 	"os"
 	"sort"
+	"strings"
 )
 
 var listCmd = &cobra.Command{
@@ -14,6 +15,7 @@ var listCmd = &cobra.Command{
 	Short: i18n.T("list.short"),
 	Run: func(cmd *cobra.Command, args []string) {
 		filterGroup, _ := cmd.Flags().GetString("group")
+		selector, _ := cmd.Flags().GetString("selector")
 
 		connections, err := config.LoadConnections()
 		if err != nil {
@@ -26,12 +28,23 @@ var listCmd = &cobra.Command{
 			return
 		}
 
+		var sel config.Selector
+		if selector != "" {
+			if sel, err = config.ParseSelector(selector); err != nil {
+				fmt.Println(i18n.TWith("selector.error.invalid", map[string]interface{}{"Error": err}))
+				os.Exit(1)
+			}
+		}
+
 		groups := make(map[string][]config.Connection)
 		var ungrouped []config.Connection
 		for _, c := range connections {
 			if filterGroup != "" && c.Group != filterGroup {
 				continue
 			}
+			if selector != "" && !sel.Matches(c) {
+				continue
+			}
 			if c.Group != "" {
 				groups[c.Group] = append(groups[c.Group], c)
 			} else {
@@ -40,7 +53,9 @@ var listCmd = &cobra.Command{
 		}
 
 		if len(groups) == 0 && len(ungrouped) == 0 {
-			if filterGroup != "" {
+			if selector != "" {
+				fmt.Println(i18n.TWith("selector.error.none", map[string]interface{}{"Selector": selector}))
+			} else if filterGroup != "" {
 				fmt.Println(i18n.TWith("list.no.connections.group", map[string]interface{}{"Group": filterGroup}))
 			} else {
 				fmt.Println(i18n.T("list.no.connections.found"))
@@ -85,16 +100,21 @@ func printConnectionInfo(c config.Connection, isGrouped bool) {
 	if isGrouped {
 		indent = "  "
 	}
-	fmt.Println(i18n.TWith("list.connection.info", map[string]interface{}{
+	info := i18n.TWith("list.connection.info", map[string]interface{}{
 		"Indent":     indent,
 		"Name":       c.Name,
 		"User":       c.User,
 		"Host":       c.Host,
 		"Port":       c.Port,
 		"AuthMethod": authMethod,
-	}))
+	})
+	if len(c.Tags) > 0 {
+		info += " [" + strings.Join(c.Tags, ", ") + "]"
+	}
+	fmt.Println(info)
 }
 
 func init() {
 	listCmd.Flags().StringP("group", "g", "", i18n.T("list.flag.group"))
+	listCmd.Flags().StringP("selector", "s", "", i18n.T("list.flag.selector"))
 }
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"os"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: i18n.T("migrate.short"),
+	Long:  i18n.T("migrate.long"),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		check, _ := cmd.Flags().GetBool("check")
+
+		var m *config.Migration
+		var err error
+		if check {
+			m, err = config.CheckMigration()
+		} else {
+			m, err = config.Migrate()
+		}
+		if err != nil {
+			fmt.Println(i18n.TWith("migrate.error", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+		if m == nil {
+			fmt.Println(i18n.TWith("migrate.up.to.date", map[string]interface{}{"Version": config.ConfigVersion}))
+			return
+		}
+
+		data := map[string]interface{}{
+			"Path":        m.Path,
+			"From":        m.From,
+			"To":          m.To,
+			"Connections": m.Connections,
+			"Backup":      m.Backup,
+		}
+		if check {
+			fmt.Println(i18n.TWith("migrate.check.header", data))
+		} else {
+			fmt.Println(i18n.TWith("migrate.done.header", data))
+		}
+		for v := m.From; v < m.To; v++ {
+			fmt.Println("  - " + i18n.TWith(fmt.Sprintf("migrate.step.%d", v), data))
+		}
+		fmt.Println("  - " + i18n.TWith("migrate.step.backup", data))
+		if check {
+			// Let scripts detect that a migration is pending.
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	migrateCmd.Flags().Bool("check", false, i18n.T("migrate.flag.check"))
+	configCmd.AddCommand(migrateCmd)
+}
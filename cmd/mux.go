@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"gossh/internal/i18n"
+	"gossh/internal/ssh"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var muxCmd = &cobra.Command{
+	Use:   "mux",
+	Short: i18n.T("mux.short"),
+	Long:  i18n.T("mux.long"),
+}
+
+var muxStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: i18n.T("mux.status.short"),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		statuses, err := ssh.MuxStatuses()
+		if err != nil {
+			fmt.Println(i18n.TWith("mux.error", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+		if len(statuses) == 0 {
+			fmt.Println(i18n.T("mux.status.none"))
+			return
+		}
+
+		fmt.Println(i18n.T("mux.status.running"))
+		for _, s := range statuses {
+			persist := s.Persist
+			if persist == "yes" {
+				persist = i18n.T("mux.status.persist.forever")
+			}
+			fmt.Println(i18n.TWith("mux.status.entry", map[string]interface{}{
+				"Name":    s.Name,
+				"User":    s.User,
+				"Host":    s.Host,
+				"Port":    s.Port,
+				"PID":     s.PID,
+				"Clients": s.Clients,
+				"Uptime":  time.Since(s.Started).Round(time.Second),
+				"Persist": persist,
+			}))
+		}
+	},
+}
+
+var muxStopCmd = &cobra.Command{
+	Use:   "stop [name]",
+	Short: i18n.T("mux.stop.short"),
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		stopped, err := ssh.StopMux(name)
+		if err != nil {
+			fmt.Println(i18n.TWith("mux.error", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+		if len(stopped) == 0 {
+			fmt.Println(i18n.T("mux.stop.none"))
+			return
+		}
+		for _, n := range stopped {
+			fmt.Println(i18n.TWith("mux.stop.success", map[string]interface{}{"Name": n}))
+		}
+	},
+}
+
+// muxServeCmd is run in the background by gossh itself to hold a connection.
+var muxServeCmd = &cobra.Command{
+	Use:    "serve",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := ssh.ServeMux(os.Stdin, os.Stdout); err != nil {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	muxCmd.AddCommand(muxStatusCmd)
+	muxCmd.AddCommand(muxStopCmd)
+	muxCmd.AddCommand(muxServeCmd)
+}
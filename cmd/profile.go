@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: i18n.T("profile.short"),
+	Long:  i18n.T("profile.long"),
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: i18n.T("profile.list.short"),
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		profiles, err := config.Profiles()
+		if err != nil {
+			fmt.Println(i18n.TWith("profile.error", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+		fmt.Println(i18n.TWith("profile.list.header", map[string]interface{}{"Dir": config.BaseDir()}))
+		for _, name := range profiles {
+			marker := " "
+			if name == config.Profile() {
+				marker = "*"
+			}
+			fmt.Printf("%s %s\n", marker, name)
+		}
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: i18n.T("profile.use.short"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if err := config.SetDefaultProfile(name); err != nil {
+			fmt.Println(i18n.TWith("profile.error", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+		fmt.Println(i18n.TWith("profile.use.success", map[string]interface{}{"Name": name}))
+		if env := os.Getenv("GOSSH_PROFILE"); env != "" && env != name {
+			fmt.Println(i18n.TWith("profile.use.env.override", map[string]interface{}{"Name": env}))
+		}
+	},
+}
+
+func init() {
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+}
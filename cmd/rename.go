@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"os"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <old> <new>",
+	Short: i18n.T("rename.short"),
+	Long:  i18n.T("rename.long"),
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		oldName, newName := args[0], args[1]
+		if err := config.RenameConnection(oldName, newName); err != nil {
+			fmt.Println(i18n.TWith("rename.error.renaming", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+		fmt.Println(i18n.TWith("rename.success", map[string]interface{}{"Old": oldName, "New": newName}))
+	},
+}
+
+func init() {
+	configCmd.AddCommand(renameCmd)
+}
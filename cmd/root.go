@@ -19,6 +19,19 @@ func init() {
 		Short:   i18n.T("root.short"),
 		Version: version,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			dir, _ := cmd.Flags().GetString("config-dir")
+			if dir == "" {
+				dir = config.BaseDir()
+			}
+			profileErr := config.SetBaseDir(dir)
+			if name, _ := cmd.Flags().GetString("profile"); name != "" {
+				profileErr = config.UseProfile(name)
+			}
+			if profileErr != nil {
+				fmt.Println(i18n.TWith("profile.error", map[string]interface{}{"Error": profileErr}))
+				os.Exit(1)
+			}
+
 			if r := cmd.Root(); r != nil {
 				r.Short = i18n.T("root.short")
 				for _, c := range r.Commands() {
@@ -38,6 +51,21 @@ func init() {
 					case "test":
 						c.Short = i18n.T("test.short")
 						c.Long = i18n.T("test.long")
+					case "sftp":
+						c.Short = i18n.T("sftp.short")
+						c.Long = i18n.T("sftp.long")
+					case "edit":
+						c.Short = i18n.T("edit.short")
+						c.Long = i18n.T("edit.long")
+					case "tail":
+						c.Short = i18n.T("tail.short")
+						c.Long = i18n.T("tail.long")
+					case "mux":
+						c.Short = i18n.T("mux.short")
+						c.Long = i18n.T("mux.long")
+					case "profile":
+						c.Short = i18n.T("profile.short")
+						c.Long = i18n.T("profile.long")
 					case "groups":
 						c.Short = i18n.T("groups.short")
 					case "help":
@@ -59,6 +87,49 @@ func init() {
 							case "export":
 								sc.Short = i18n.T("export.short")
 								sc.Long = i18n.T("export.long")
+							case "import-ssh":
+								sc.Short = i18n.T("import.ssh.short")
+								sc.Long = i18n.T("import.ssh.long")
+							case "import-ansible":
+								sc.Short = i18n.T("import.ansible.short")
+								sc.Long = i18n.T("import.ansible.long")
+							case "set":
+								sc.Short = i18n.T("set.short")
+								sc.Long = i18n.T("set.long")
+							case "edit":
+								sc.Short = i18n.T("config.edit.short")
+								sc.Long = i18n.T("config.edit.long")
+							case "rename":
+								sc.Short = i18n.T("rename.short")
+								sc.Long = i18n.T("rename.long")
+							case "migrate":
+								sc.Short = i18n.T("migrate.short")
+								sc.Long = i18n.T("migrate.long")
+							case "show":
+								sc.Short = i18n.T("show.short")
+								sc.Long = i18n.T("show.long")
+							case "defaults":
+								sc.Short = i18n.T("defaults.short")
+								sc.Long = i18n.T("defaults.long")
+							case "convert":
+								sc.Short = i18n.T("convert.short")
+								sc.Long = i18n.T("convert.long")
+							}
+						}
+						if c.Name() == "mux" {
+							switch sc.Name() {
+							case "status":
+								sc.Short = i18n.T("mux.status.short")
+							case "stop":
+								sc.Short = i18n.T("mux.stop.short")
+							}
+						}
+						if c.Name() == "profile" {
+							switch sc.Name() {
+							case "list":
+								sc.Short = i18n.T("profile.list.short")
+							case "use":
+								sc.Short = i18n.T("profile.use.short")
 							}
 						}
 						if c.Name() == "password" {
@@ -78,6 +149,9 @@ func init() {
 		},
 	}
 
+	rootCmd.PersistentFlags().String("config-dir", "", i18n.T("root.flag.config.dir"))
+	rootCmd.PersistentFlags().String("profile", "", i18n.T("root.flag.profile"))
+
 	rootCmd.SetVersionTemplate(`{{printf "%s\n" .Version}}`)
 
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
@@ -96,6 +170,11 @@ func init() {
 	rootCmd.AddCommand(testCmd)
 	rootCmd.AddCommand(groupsCmd)
 	rootCmd.AddCommand(copyCmd)
+	rootCmd.AddCommand(sftpCmd)
+	rootCmd.AddCommand(editCmd)
+	rootCmd.AddCommand(tailCmd)
+	rootCmd.AddCommand(muxCmd)
+	rootCmd.AddCommand(profileCmd)
 }
 
 func Execute() {
@@ -12,7 +12,7 @@ import (
 )
 
 var scpCmd = &cobra.Command{
-	Use:   "scp [source] [destination]",
+	Use:   "scp [source...] [destination]",
 	Short: i18n.T("scp.short"),
 	Long:  i18n.T("scp.long"),
 	Run:   runScp,
@@ -21,10 +21,19 @@ var scpCmd = &cobra.Command{
 func init() {
 	scpCmd.Flags().BoolP("recursive", "r", false, i18n.T("scp.flag.recursive"))
 	scpCmd.Flags().BoolP("force", "f", false, i18n.T("scp.flag.force"))
+	scpCmd.Flags().Bool("direct", false, i18n.T("scp.flag.direct"))
+	scpCmd.Flags().Bool("verify", false, i18n.T("scp.flag.verify"))
+	scpCmd.Flags().String("limit", "", i18n.T("scp.flag.limit"))
+	scpCmd.Flags().Bool("archive", false, i18n.T("scp.flag.archive"))
+	scpCmd.Flags().String("compress", "none", i18n.T("scp.flag.compress"))
+	scpCmd.Flags().StringP("group", "g", "", i18n.T("scp.flag.group"))
+	scpCmd.Flags().StringP("selector", "s", "", i18n.T("scp.flag.selector"))
+	scpCmd.Flags().String("overwrite", ssh.OverwriteError, i18n.T("scp.flag.overwrite"))
+	scpCmd.Flags().Bool("dry-run", false, i18n.T("scp.flag.dry.run"))
 }
 
 func runScp(cmd *cobra.Command, args []string) {
-	if len(args) != 2 {
+	if len(args) < 2 {
 		fmt.Println(i18n.T("scp.error.both.paths"))
 		_ = cmd.Help()
 		os.Exit(1)
@@ -32,15 +41,50 @@ func runScp(cmd *cobra.Command, args []string) {
 
 	recursive, _ := cmd.Flags().GetBool("recursive")
 	force, _ := cmd.Flags().GetBool("force")
-	source := args[0]
-	destination := args[1]
+	direct, _ := cmd.Flags().GetBool("direct")
+	verify, _ := cmd.Flags().GetBool("verify")
+	limit, _ := cmd.Flags().GetString("limit")
+	archive, _ := cmd.Flags().GetBool("archive")
+	compress, _ := cmd.Flags().GetString("compress")
+	group, _ := cmd.Flags().GetString("group")
+	selector, _ := cmd.Flags().GetString("selector")
+	overwrite, _ := cmd.Flags().GetString("overwrite")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	sources := args[:len(args)-1]
+	destination := args[len(args)-1]
 
-	sourceHasColon := strings.Contains(source, ":")
+	// A destination of @group:path, or --group, uploads to every host of the group;
+	// --selector uploads to every host matching the selector.
+	if group != "" && selector != "" {
+		fmt.Println(i18n.T("scp.error.group.selector"))
+		os.Exit(1)
+	}
+	if strings.HasPrefix(destination, "@") {
+		if selector != "" {
+			fmt.Println(i18n.T("scp.error.group.selector"))
+			os.Exit(1)
+		}
+		parts := strings.SplitN(destination[1:], ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			fmt.Println(i18n.T("scp.error.invalid.format"))
+			os.Exit(1)
+		}
+		group, destination = parts[0], parts[1]
+	} else if group != "" || selector != "" {
+		destination = strings.TrimPrefix(destination, ":")
+	}
+	fanOut := group != "" || selector != ""
+
+	// All sources must be local, or remote on the same connection.
+	sourceConnName, sourcePaths, sourceHasColon := splitSources(sources)
 	destHasColon := strings.Contains(destination, ":")
 
-	if sourceHasColon && destHasColon {
-		fmt.Println(i18n.T("scp.error.both.remote"))
-		os.Exit(1)
+	if fanOut {
+		if sourceHasColon {
+			fmt.Println(i18n.T("scp.error.group.remote.source"))
+			os.Exit(1)
+		}
+		destHasColon = true
 	}
 
 	if !sourceHasColon && !destHasColon {
@@ -48,40 +92,143 @@ func runScp(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	if direct && !(sourceHasColon && destHasColon) {
+		fmt.Println(i18n.T("scp.error.direct.requires.remote"))
+		os.Exit(1)
+	}
+
+	if direct && verify {
+		fmt.Println(i18n.T("scp.error.direct.verify"))
+		os.Exit(1)
+	}
+
+	if !isOverwritePolicy(overwrite) {
+		fmt.Println(i18n.TWith("scp.error.invalid.overwrite", map[string]interface{}{
+			"Value":    overwrite,
+			"Policies": strings.Join(ssh.OverwritePolicies, ", "),
+		}))
+		os.Exit(1)
+	}
+	if force {
+		if cmd.Flags().Changed("overwrite") && overwrite != ssh.OverwriteAlways {
+			fmt.Println(i18n.T("scp.error.force.overwrite"))
+			os.Exit(1)
+		}
+		overwrite = ssh.OverwriteAlways
+	}
+	if direct && (dryRun || cmd.Flags().Changed("overwrite")) {
+		fmt.Println(i18n.T("scp.error.direct.overwrite"))
+		os.Exit(1)
+	}
+	if fanOut && overwrite == ssh.OverwriteAsk && !dryRun {
+		fmt.Println(i18n.T("scp.error.group.ask"))
+		os.Exit(1)
+	}
+
+	opts := ssh.TransferOptions{
+		Recursive: recursive,
+		Overwrite: overwrite,
+		DryRun:    dryRun,
+		Verify:    verify,
+	}
+
+	if archive {
+		if verify || direct {
+			fmt.Println(i18n.T("scp.error.archive.conflict"))
+			os.Exit(1)
+		}
+		switch compress {
+		case "none":
+			opts.Archive = ssh.ArchiveTar
+		case "gzip":
+			opts.Archive = ssh.ArchiveGzip
+		case "zstd":
+			opts.Archive = ssh.ArchiveZstd
+		default:
+			fmt.Println(i18n.TWith("scp.error.invalid.compress", map[string]interface{}{"Value": compress}))
+			os.Exit(1)
+		}
+	}
+
 	connections, err := config.LoadConnections()
 	if err != nil {
 		fmt.Println(i18n.TWith("error.loading.connections", map[string]interface{}{"Error": err}))
 		os.Exit(1)
 	}
 
-	if sourceHasColon {
-		parts := strings.SplitN(source, ":", 2)
-		if len(parts) != 2 {
-			fmt.Println(i18n.T("scp.error.invalid.format"))
+	if group != "" {
+		members := groupConnections(connections, group)
+		if len(members) == 0 {
+			fmt.Println(i18n.TWith("scp.error.group.empty", map[string]interface{}{"Group": group}))
 			os.Exit(1)
 		}
-		connName := parts[0]
-		remotePath := parts[1]
+		target := i18n.TWith("scp.fanout.group", map[string]interface{}{"Group": group})
+		runFanOutUpload(members, target, sourcePaths, destination, limit, opts)
+		return
+	}
+	if selector != "" {
+		members := selectConnections(connections, selector)
+		target := i18n.TWith("scp.fanout.selector", map[string]interface{}{"Selector": selector})
+		runFanOutUpload(members, target, sourcePaths, destination, limit, opts)
+		return
+	}
+
+	if sourceHasColon && destHasColon {
+		dstParts := strings.SplitN(destination, ":", 2)
+		dstPath := dstParts[1]
+
+		srcConn := findConnection(connections, sourceConnName)
+		if srcConn == nil {
+			fmt.Println(i18n.TWith("error.connection.not.found", map[string]interface{}{"Name": sourceConnName}))
+			os.Exit(1)
+		}
+		dstConn := findConnection(connections, dstParts[0])
+		if dstConn == nil {
+			fmt.Println(i18n.TWith("error.connection.not.found", map[string]interface{}{"Name": dstParts[0]}))
+			os.Exit(1)
+		}
+		opts.Limiter = transferLimiter(limit, srcConn, dstConn)
+
+		fmt.Println(i18n.TWith("scp.copying", map[string]interface{}{
+			"SrcUser": srcConn.User,
+			"SrcHost": srcConn.Host,
+			"SrcPath": strings.Join(sourcePaths, " "),
+			"DstUser": dstConn.User,
+			"DstHost": dstConn.Host,
+			"DstPath": dstPath,
+		}))
+		if direct {
+			err = ssh.DirectCopyRemoteToRemote(srcConn, sourcePaths, dstConn, dstPath, recursive)
+		} else {
+			err = ssh.CopyRemoteToRemote(srcConn, sourcePaths, dstConn, dstPath, opts)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(transferDone(dryRun, "scp.copy.success"))
+	} else if sourceHasColon {
 		localPath := destination
 
-		conn := findConnection(connections, connName)
+		conn := findConnection(connections, sourceConnName)
 		if conn == nil {
-			fmt.Println(i18n.TWith("error.connection.not.found", map[string]interface{}{"Name": connName}))
+			fmt.Println(i18n.TWith("error.connection.not.found", map[string]interface{}{"Name": sourceConnName}))
 			os.Exit(1)
 		}
+		opts.Limiter = transferLimiter(limit, conn)
 
 		fmt.Println(i18n.TWith("scp.downloading", map[string]interface{}{
 			"User":  conn.User,
 			"Host":  conn.Host,
-			"Path":  remotePath,
+			"Path":  strings.Join(sourcePaths, " "),
 			"Local": localPath,
 		}))
-		err = ssh.DownloadFileWithOpts(conn, remotePath, localPath, recursive, force)
+		err = ssh.DownloadFilesWithOpts(conn, sourcePaths, localPath, opts)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Println(i18n.T("scp.download.success"))
+		fmt.Println(transferDone(dryRun, "scp.download.success"))
 	} else {
 		parts := strings.SplitN(destination, ":", 2)
 		if len(parts) != 2 {
@@ -90,27 +237,143 @@ func runScp(cmd *cobra.Command, args []string) {
 		}
 		connName := parts[0]
 		remotePath := parts[1]
-		localPath := source
 
 		conn := findConnection(connections, connName)
 		if conn == nil {
 			fmt.Println(i18n.TWith("error.connection.not.found", map[string]interface{}{"Name": connName}))
 			os.Exit(1)
 		}
+		opts.Limiter = transferLimiter(limit, conn)
 
 		fmt.Println(i18n.TWith("scp.uploading", map[string]interface{}{
-			"Local": localPath,
+			"Local": strings.Join(sourcePaths, " "),
 			"User":  conn.User,
 			"Host":  conn.Host,
 			"Path":  remotePath,
 		}))
-		err = ssh.UploadFileWithOpts(conn, localPath, remotePath, recursive, force)
+		err = ssh.UploadFilesWithOpts(conn, sourcePaths, remotePath, opts)
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Println(i18n.T("scp.upload.success"))
+		fmt.Println(transferDone(dryRun, "scp.upload.success"))
+	}
+}
+
+// runFanOutUpload uploads local files to several connections concurrently and prints a
+// summary. It exits with an error status if any host failed.
+func runFanOutUpload(members []*config.Connection, target string, sourcePaths []string, remotePath string, limit string, opts ssh.TransferOptions) {
+	// The limit is shared by all hosts, so it caps the total upload rate.
+	opts.Limiter = transferLimiter(limit, members...)
+
+	fmt.Println(i18n.TWith("scp.fanout.uploading", map[string]interface{}{
+		"Local":  strings.Join(sourcePaths, " "),
+		"Target": target,
+		"Count":  len(members),
+		"Path":   remotePath,
+	}))
+	results, err := ssh.FanOutUpload(members, sourcePaths, remotePath, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var failed []ssh.FanOutResult
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	fmt.Println(i18n.TWith("scp.fanout.summary", map[string]interface{}{
+		"Succeeded": len(results) - len(failed),
+		"Failed":    len(failed),
+	}))
+	for _, result := range failed {
+		fmt.Printf("  %s: %v\n", result.Conn.Name, result.Err)
+	}
+	if len(failed) > 0 {
+		os.Exit(1)
+	}
+	if opts.DryRun {
+		fmt.Println(i18n.T("scp.dry.run.done"))
+	}
+}
+
+// transferDone returns the message printed after a successful transfer.
+func transferDone(dryRun bool, successKey string) string {
+	if dryRun {
+		return i18n.T("scp.dry.run.done")
+	}
+	return i18n.T(successKey)
+}
+
+// isOverwritePolicy reports whether policy is a valid --overwrite value.
+func isOverwritePolicy(policy string) bool {
+	for _, p := range ssh.OverwritePolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// splitSources separates the connection name from the paths of the scp sources.
+// It exits if local and remote sources are mixed or if remote sources refer to
+// different connections.
+func splitSources(sources []string) (connName string, paths []string, remote bool) {
+	remote = strings.Contains(sources[0], ":")
+	for _, source := range sources {
+		if strings.Contains(source, ":") != remote {
+			fmt.Println(i18n.T("scp.error.mixed.sources"))
+			os.Exit(1)
+		}
+		if !remote {
+			paths = append(paths, source)
+			continue
+		}
+		parts := strings.SplitN(source, ":", 2)
+		if connName != "" && parts[0] != connName {
+			fmt.Println(i18n.T("scp.error.mixed.sources"))
+			os.Exit(1)
+		}
+		connName = parts[0]
+		paths = append(paths, parts[1])
+	}
+	return connName, paths, remote
+}
+
+// transferLimiter returns the bandwidth limiter for a transfer. The --limit flag takes
+// precedence; otherwise the lowest limit configured on the connections involved is used.
+// It returns nil when no limit applies.
+func transferLimiter(limit string, conns ...*config.Connection) *ssh.RateLimiter {
+	var bytesPerSecond int64
+	if limit != "" {
+		rate, err := ssh.ParseBandwidth(limit)
+		if err != nil {
+			fmt.Println(i18n.TWith("scp.error.invalid.limit", map[string]interface{}{"Limit": limit}))
+			os.Exit(1)
+		}
+		bytesPerSecond = rate
+	} else {
+		for _, conn := range conns {
+			if conn.BandwidthLimit == "" {
+				continue
+			}
+			rate, err := ssh.ParseBandwidth(conn.BandwidthLimit)
+			if err != nil {
+				fmt.Println(i18n.TWith("scp.error.invalid.limit", map[string]interface{}{"Limit": conn.BandwidthLimit}))
+				os.Exit(1)
+			}
+			if bytesPerSecond == 0 || rate < bytesPerSecond {
+				bytesPerSecond = rate
+			}
+		}
+	}
+
+	if bytesPerSecond == 0 {
+		return nil
 	}
+	return ssh.NewRateLimiter(bytesPerSecond)
 }
 
 func findConnection(connections []config.Connection, name string) *config.Connection {
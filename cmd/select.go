@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"os"
+	"strings"
+)
+
+// selectConnections returns the connections matching a selector expression, exiting
+// with an error if the expression is invalid or matches nothing.
+func selectConnections(connections []config.Connection, expr string) []*config.Connection {
+	sel, err := config.ParseSelector(expr)
+	if err != nil {
+		fmt.Println(i18n.TWith("selector.error.invalid", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+	matched := config.Select(connections, sel)
+	if len(matched) == 0 {
+		fmt.Println(i18n.TWith("selector.error.none", map[string]interface{}{"Selector": expr}))
+		os.Exit(1)
+	}
+	return matched
+}
+
+// checkTags validates tags given on the command line, exiting with an error if one is
+// invalid. A later tag replaces an earlier one with the same key.
+func checkTags(tags []string) []string {
+	var result []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if err := config.ValidateTag(tag); err != nil {
+			fmt.Println(i18n.TWith("tag.error.invalid", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+		result = append(withoutTagKey(result, tag), tag)
+	}
+	return result
+}
+
+// withoutTagKey returns tags without those having the same key as tag.
+func withoutTagKey(tags []string, tag string) []string {
+	key, _, _ := strings.Cut(tag, "=")
+	var result []string
+	for _, t := range tags {
+		if k, _, _ := strings.Cut(t, "="); k != key {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// removeTags returns tags without those named in remove. An entry without a value
+// removes the tag or label with that key; key=value removes only that exact label.
+func removeTags(tags, remove []string) []string {
+	for _, r := range remove {
+		if !strings.Contains(r, "=") {
+			tags = withoutTagKey(tags, r)
+			continue
+		}
+		var result []string
+		for _, t := range tags {
+			if t != r {
+				result = append(result, t)
+			}
+		}
+		tags = result
+	}
+	return tags
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"gossh/internal/ssh"
+	"os"
+)
+
+var setCmd = &cobra.Command{
+	Use:   "set <name>",
+	Short: i18n.T("set.short"),
+	Long:  i18n.T("set.long"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		doc, conn := loadConnection(args[0])
+		flags := cmd.Flags()
+		if !localFlagsChanged(cmd) {
+			fmt.Println(i18n.T("set.error.no.changes"))
+			os.Exit(1)
+		}
+		if flags.Changed("key") && flags.Changed("use-password") {
+			keyPath, _ := flags.GetString("key")
+			credAlias, _ := flags.GetString("use-password")
+			if keyPath != "" && credAlias != "" {
+				fmt.Println(i18n.T("add.error.key.password.together"))
+				os.Exit(1)
+			}
+		}
+
+		if flags.Changed("group") {
+			conn.Group, _ = flags.GetString("group")
+		}
+		if flags.Changed("user") {
+			conn.User, _ = flags.GetString("user")
+		}
+		if flags.Changed("host") {
+			conn.Host, _ = flags.GetString("host")
+		}
+		if flags.Changed("port") {
+			conn.Port, _ = flags.GetInt("port")
+		}
+		if flags.Changed("key") {
+			conn.KeyPath, _ = flags.GetString("key")
+			if conn.KeyPath != "" {
+				conn.CredentialAlias = ""
+			}
+		}
+		if flags.Changed("use-password") {
+			conn.CredentialAlias, _ = flags.GetString("use-password")
+			if conn.CredentialAlias != "" {
+				conn.KeyPath = ""
+			}
+		}
+		if flags.Changed("limit") {
+			conn.BandwidthLimit, _ = flags.GetString("limit")
+		}
+		if flags.Changed("control-persist") {
+			conn.ControlPersist, _ = flags.GetString("control-persist")
+		}
+		if flags.Changed("proxy-jump") {
+			conn.ProxyJump, _ = flags.GetString("proxy-jump")
+		}
+		if flags.Changed("tag") {
+			tags, _ := flags.GetStringSlice("tag")
+			conn.Tags = checkTags(tags)
+		}
+		if flags.Changed("add-tag") {
+			tags, _ := flags.GetStringSlice("add-tag")
+			conn.Tags = checkTags(append(conn.Tags, tags...))
+		}
+		if flags.Changed("remove-tag") {
+			tags, _ := flags.GetStringSlice("remove-tag")
+			conn.Tags = removeTags(conn.Tags, tags)
+		}
+
+		saveUpdatedConnection(doc, *conn)
+	},
+}
+
+// localFlagsChanged reports whether any of the command's own flags was given, ignoring
+// global flags such as --profile.
+func localFlagsChanged(cmd *cobra.Command) bool {
+	changed := false
+	cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			changed = true
+		}
+	})
+	return changed
+}
+
+// loadConnection returns the configuration and the saved connection with the given
+// name as it is stored, without inherited settings, exiting with an error if it does
+// not exist.
+func loadConnection(name string) (*config.Document, *config.Connection) {
+	doc, err := config.LoadDocument()
+	if err != nil {
+		fmt.Println(i18n.TWith("error.loading.connections", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+	conn := findConnection(doc.Connections, name)
+	if conn == nil {
+		fmt.Println(i18n.TWith("error.connection.not.found", map[string]interface{}{"Name": name}))
+		os.Exit(1)
+	}
+	return doc, conn
+}
+
+// saveUpdatedConnection validates an edited connection the same way add does and
+// writes it back to the configuration file.
+func saveUpdatedConnection(doc *config.Document, conn config.Connection) {
+	if conn.Host == "" || doc.Resolve(conn).User == "" {
+		fmt.Println(i18n.T("set.error.user.host.required"))
+		os.Exit(1)
+	}
+	validateSettings(conn.Settings())
+
+	if err := config.UpdateConnection(conn); err != nil {
+		fmt.Println(i18n.TWith("set.error.updating", map[string]interface{}{"Error": err}))
+		os.Exit(1)
+	}
+	fmt.Println(i18n.TWith("set.success", map[string]interface{}{"Name": conn.Name}))
+}
+
+// validateSettings checks connection settings or defaults, exiting with an error if
+// one is invalid.
+func validateSettings(s config.Defaults) {
+	if s.Port < 0 || s.Port > 65535 {
+		fmt.Println(i18n.T("add.invalid.port"))
+		os.Exit(1)
+	}
+	if s.KeyPath != "" && s.CredentialAlias != "" {
+		fmt.Println(i18n.T("add.error.key.password.together"))
+		os.Exit(1)
+	}
+	if s.CredentialAlias != "" && !credentialAliasExists(s.CredentialAlias) {
+		fmt.Println(i18n.TWith("add.error.credential.not.found", map[string]interface{}{"Alias": s.CredentialAlias}))
+		os.Exit(1)
+	}
+	if s.BandwidthLimit != "" {
+		if _, err := ssh.ParseBandwidth(s.BandwidthLimit); err != nil {
+			fmt.Println(i18n.TWith("scp.error.invalid.limit", map[string]interface{}{"Limit": s.BandwidthLimit}))
+			os.Exit(1)
+		}
+	}
+	if _, _, err := ssh.ParseControlPersist(s.ControlPersist); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func init() {
+	setCmd.Flags().StringP("group", "g", "", i18n.T("add.flag.group"))
+	setCmd.Flags().StringP("user", "u", "", i18n.T("add.flag.user"))
+	setCmd.Flags().StringP("host", "H", "", i18n.T("add.flag.host"))
+	setCmd.Flags().IntP("port", "p", 0, i18n.T("add.flag.port"))
+	setCmd.Flags().StringP("key", "k", "", i18n.T("add.flag.key"))
+	setCmd.Flags().StringP("use-password", "P", "", i18n.T("add.flag.use-password"))
+	setCmd.Flags().String("limit", "", i18n.T("add.flag.limit"))
+	setCmd.Flags().String("control-persist", "", i18n.T("add.flag.control.persist"))
+	setCmd.Flags().StringP("proxy-jump", "J", "", i18n.T("add.flag.proxy.jump"))
+	setCmd.Flags().StringSliceP("tag", "t", nil, i18n.T("set.flag.tag"))
+	setCmd.Flags().StringSlice("add-tag", nil, i18n.T("set.flag.add.tag"))
+	setCmd.Flags().StringSlice("remove-tag", nil, i18n.T("set.flag.remove.tag"))
+	configCmd.AddCommand(setCmd)
+}
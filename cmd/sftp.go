@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"gossh/internal/ssh"
+	"os"
+)
+
+var sftpCmd = &cobra.Command{
+	Use:   "sftp <name>",
+	Short: i18n.T("sftp.short"),
+	Long:  i18n.T("sftp.long"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		connectionName := args[0]
+
+		connections, err := config.LoadConnections()
+		if err != nil {
+			fmt.Println(i18n.TWith("error.loading.connections", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+
+		conn := findConnection(connections, connectionName)
+		if conn == nil {
+			fmt.Println(i18n.TWith("error.connection.not.found", map[string]interface{}{"Name": connectionName}))
+			os.Exit(1)
+		}
+
+		if err := ssh.SFTPShell(conn); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"strconv"
+	"strings"
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show <name>",
+	Short: i18n.T("show.short"),
+	Long:  i18n.T("show.long"),
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		resolved, _ := cmd.Flags().GetBool("resolved")
+		doc, conn := loadConnection(args[0])
+
+		fmt.Printf("%-18s %s\n", i18n.T("show.name")+":", conn.Name)
+		fmt.Printf("%-18s %s\n", i18n.T("config.edit.host")+":", conn.Host)
+		if conn.Group != "" {
+			fmt.Printf("%-18s %s\n", i18n.T("config.edit.group")+":", conn.Group)
+		}
+		if len(conn.Tags) > 0 {
+			fmt.Printf("%-18s %s\n", i18n.T("show.tags")+":", strings.Join(conn.Tags, ", "))
+		}
+
+		if !resolved {
+			printSettings(conn.Settings(), nil, "")
+			return
+		}
+		r := doc.Resolve(*conn)
+		printSettings(r.Settings(), r.Sources, conn.Group)
+	},
+}
+
+// printSettings prints the settings that have a value. If sources is given, each value
+// is followed by where it came from.
+func printSettings(s config.Defaults, sources map[string]string, group string) {
+	port := ""
+	if s.Port != 0 {
+		port = strconv.Itoa(s.Port)
+	}
+	rows := []struct{ key, label, value string }{
+		{"user", "config.edit.user", s.User},
+		{"port", "config.edit.port", port},
+		{"key_path", "config.edit.key", s.KeyPath},
+		{"credential_alias", "config.edit.password", s.CredentialAlias},
+		{"bandwidth_limit", "config.edit.limit", s.BandwidthLimit},
+		{"control_persist", "config.edit.control.persist", s.ControlPersist},
+		{"proxy_jump", "config.edit.proxy.jump", s.ProxyJump},
+	}
+	for _, row := range rows {
+		if row.value == "" {
+			continue
+		}
+		line := fmt.Sprintf("%-18s %s", i18n.T(row.label)+":", row.value)
+		if source, ok := sources[row.key]; ok {
+			line += "  (" + i18n.TWith("show.source."+source, map[string]interface{}{"Group": group}) + ")"
+		}
+		fmt.Println(line)
+	}
+}
+
+func init() {
+	showCmd.Flags().Bool("resolved", false, i18n.T("show.flag.resolved"))
+	configCmd.AddCommand(showCmd)
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"gossh/internal/ssh"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var tailCmd = &cobra.Command{
+	Use:   "tail [-f] <name|group>:<path>... | -g <group> <path>...",
+	Short: i18n.T("tail.short"),
+	Long:  i18n.T("tail.long"),
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		follow, _ := cmd.Flags().GetBool("follow")
+		lines, _ := cmd.Flags().GetInt("lines")
+		group, _ := cmd.Flags().GetString("group")
+		colorMode, _ := cmd.Flags().GetString("color")
+
+		connections, err := config.LoadConnections()
+		if err != nil {
+			fmt.Println(i18n.TWith("error.loading.connections", map[string]interface{}{"Error": err}))
+			os.Exit(1)
+		}
+
+		var targets []ssh.TailTarget
+		if group != "" {
+			members := groupConnections(connections, group)
+			if len(members) == 0 {
+				fmt.Println(i18n.TWith("list.no.connections.group", map[string]interface{}{"Group": group}))
+				os.Exit(1)
+			}
+			for _, path := range args {
+				for _, conn := range members {
+					targets = append(targets, ssh.TailTarget{Conn: conn, Path: path})
+				}
+			}
+		} else {
+			for _, arg := range args {
+				parts := strings.SplitN(arg, ":", 2)
+				if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+					fmt.Println(i18n.T("scp.error.invalid.format"))
+					os.Exit(1)
+				}
+				members := []*config.Connection{findConnection(connections, parts[0])}
+				if members[0] == nil {
+					members = groupConnections(connections, parts[0])
+				}
+				if len(members) == 0 {
+					fmt.Println(i18n.TWith("error.connection.not.found", map[string]interface{}{"Name": parts[0]}))
+					os.Exit(1)
+				}
+				for _, conn := range members {
+					targets = append(targets, ssh.TailTarget{Conn: conn, Path: parts[1]})
+				}
+			}
+		}
+
+		// Include the path in the label when a connection tails more than one file.
+		filesPerConn := make(map[string]int)
+		for _, t := range targets {
+			filesPerConn[t.Conn.Name]++
+		}
+		for i := range targets {
+			targets[i].Label = targets[i].Conn.Name
+			if filesPerConn[targets[i].Conn.Name] > 1 {
+				targets[i].Label += ":" + targets[i].Path
+			}
+		}
+
+		var color bool
+		switch colorMode {
+		case "always":
+			color = true
+		case "never":
+			color = false
+		case "auto":
+			color = terminal.IsTerminal(int(os.Stdout.Fd()))
+		default:
+			fmt.Println(i18n.TWith("tail.error.invalid.color", map[string]interface{}{"Value": colorMode}))
+			os.Exit(1)
+		}
+
+		opts := ssh.TailOptions{Follow: follow, Lines: lines, Color: color}
+		if err := ssh.TailFiles(targets, opts); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// groupConnections returns the connections that belong to the given group.
+func groupConnections(connections []config.Connection, group string) []*config.Connection {
+	var members []*config.Connection
+	for i, c := range connections {
+		if c.Group == group {
+			members = append(members, &connections[i])
+		}
+	}
+	return members
+}
+
+func init() {
+	tailCmd.Flags().BoolP("follow", "f", false, i18n.T("tail.flag.follow"))
+	tailCmd.Flags().IntP("lines", "n", 10, i18n.T("tail.flag.lines"))
+	tailCmd.Flags().StringP("group", "g", "", i18n.T("tail.flag.group"))
+	tailCmd.Flags().String("color", "auto", i18n.T("tail.flag.color"))
+}
@@ -13,9 +13,13 @@ var testCmd = &cobra.Command{
 	Use:   "test <name>",
 	Short: i18n.T("test.short"),
 	Long:  i18n.T("test.long"),
-	Args:  cobra.ExactArgs(1),
+	Args:  cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		connectionName := args[0]
+		selector, _ := cmd.Flags().GetString("selector")
+		if (selector == "") == (len(args) == 0) {
+			_ = cmd.Help()
+			os.Exit(1)
+		}
 
 		connections, err := config.LoadConnections()
 		if err != nil {
@@ -23,6 +27,12 @@ var testCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if selector != "" {
+			runSelectedTest(selectConnections(connections, selector))
+			return
+		}
+		connectionName := args[0]
+
 		var conn *config.Connection
 		for i, c := range connections {
 			if c.Name == connectionName {
@@ -44,3 +54,28 @@ var testCmd = &cobra.Command{
 		fmt.Println(i18n.TWith("test.success", map[string]interface{}{"Name": connectionName}))
 	},
 }
+
+// runSelectedTest tests each of the connections in turn and prints a summary. It exits
+// with an error status if any test failed.
+func runSelectedTest(conns []*config.Connection) {
+	failed := 0
+	for _, conn := range conns {
+		if err := ssh.TestConnection(conn); err != nil {
+			fmt.Println(i18n.TWith("test.failed", map[string]interface{}{"Name": conn.Name, "Error": err}))
+			failed++
+			continue
+		}
+		fmt.Println(i18n.TWith("test.success", map[string]interface{}{"Name": conn.Name}))
+	}
+	fmt.Println(i18n.TWith("exec.summary", map[string]interface{}{
+		"Succeeded": len(conns) - failed,
+		"Failed":    failed,
+	}))
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func init() {
+	testCmd.Flags().StringP("selector", "s", "", i18n.T("test.flag.selector"))
+}
@@ -0,0 +1,478 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AnsibleHost is a host of an Ansible inventory with the variables that apply to it.
+type AnsibleHost struct {
+	Name string
+	// Groups are the groups listing the host, in inventory order, followed by the
+	// groups containing those. The implicit all and ungrouped groups are left out.
+	Groups []string
+	Vars   map[string]string
+}
+
+// ansibleInventory is a parsed inventory, in INI or YAML format.
+type ansibleInventory struct {
+	groups     map[string]*ansibleGroup
+	groupOrder []string
+	hosts      []string
+	hostVars   map[string]map[string]string
+}
+
+type ansibleGroup struct {
+	hosts    []string
+	children []string
+	vars     map[string]string
+}
+
+// ParseAnsibleInventory reads a static Ansible inventory and returns its hosts, with
+// variables resolved the way Ansible does: variables of the all group first, then
+// those of the other groups from parents to children, then the host's own. Files
+// ending in .yml, .yaml or .json are read as YAML inventories, others as INI.
+func ParseAnsibleInventory(file string) ([]AnsibleHost, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	inv := &ansibleInventory{
+		groups:   make(map[string]*ansibleGroup),
+		hostVars: make(map[string]map[string]string),
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yml", ".yaml", ".json":
+		err = inv.parseYAML(data)
+	default:
+		err = inv.parseINI(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return inv.resolve(), nil
+}
+
+func (inv *ansibleInventory) group(name string) *ansibleGroup {
+	g, ok := inv.groups[name]
+	if !ok {
+		g = &ansibleGroup{vars: make(map[string]string)}
+		inv.groups[name] = g
+		inv.groupOrder = append(inv.groupOrder, name)
+	}
+	return g
+}
+
+func (inv *ansibleInventory) addHost(group, host string, vars map[string]string) {
+	if _, ok := inv.hostVars[host]; !ok {
+		inv.hostVars[host] = make(map[string]string)
+		inv.hosts = append(inv.hosts, host)
+	}
+	for k, v := range vars {
+		inv.hostVars[host][k] = v
+	}
+	g := inv.group(group)
+	for _, h := range g.hosts {
+		if h == host {
+			return
+		}
+	}
+	g.hosts = append(g.hosts, host)
+}
+
+func (inv *ansibleInventory) addChild(group, child string) {
+	g := inv.group(group)
+	inv.group(child)
+	for _, c := range g.children {
+		if c == child {
+			return
+		}
+	}
+	g.children = append(g.children, child)
+}
+
+// parseINI reads an inventory in INI format, with [group], [group:vars] and
+// [group:children] sections.
+func (inv *ansibleInventory) parseINI(data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	section, kind := "ungrouped", "hosts"
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section, kind = line[1:len(line)-1], "hosts"
+			if name, suffix, ok := strings.Cut(section, ":"); ok {
+				if suffix != "vars" && suffix != "children" {
+					return fmt.Errorf("line %d: invalid section [%s]", lineNo, section)
+				}
+				section, kind = name, suffix
+			}
+			inv.group(section)
+			continue
+		}
+
+		switch kind {
+		case "vars":
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				return fmt.Errorf("line %d: expected key=value in [%s:vars]", lineNo, section)
+			}
+			inv.group(section).vars[strings.TrimSpace(key)] = unquoteAnsible(strings.TrimSpace(value))
+		case "children":
+			inv.addChild(section, strings.Fields(line)[0])
+		default:
+			fields := splitAnsibleArgs(line)
+			vars := make(map[string]string)
+			for _, field := range fields[1:] {
+				if strings.HasPrefix(field, "#") {
+					break
+				}
+				key, value, ok := strings.Cut(field, "=")
+				if !ok {
+					return fmt.Errorf("line %d: expected key=value after host, found '%s'", lineNo, field)
+				}
+				vars[key] = value
+			}
+			pattern := fields[0]
+			if i := strings.LastIndex(pattern, ":"); i > 0 && !strings.Contains(pattern[i:], "]") && strings.Count(pattern, ":") == 1+strings.Count(pattern, "[") {
+				if _, err := strconv.Atoi(pattern[i+1:]); err == nil {
+					vars["ansible_port"] = pattern[i+1:]
+					pattern = pattern[:i]
+				}
+			}
+			hosts, err := expandAnsibleRange(pattern)
+			if err != nil {
+				return fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			for _, host := range hosts {
+				inv.addHost(section, host, vars)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// parseYAML reads an inventory in YAML format: a mapping of groups, each with
+// optional hosts, vars and children.
+func (inv *ansibleInventory) parseYAML(data []byte) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return err
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("expected a mapping of groups")
+	}
+	groups := root.Content[0]
+	for i := 0; i+1 < len(groups.Content); i += 2 {
+		if err := inv.parseYAMLGroup(groups.Content[i].Value, groups.Content[i+1], 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (inv *ansibleInventory) parseYAMLGroup(name string, node *yaml.Node, depth int) error {
+	if depth > 32 {
+		return fmt.Errorf("group '%s' is nested too deeply", name)
+	}
+	inv.group(name)
+	if node.Kind != yaml.MappingNode {
+		// An empty group is written as "name:" with no value.
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		switch key {
+		case "hosts":
+			if value.Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				hosts, err := expandAnsibleRange(value.Content[j].Value)
+				if err != nil {
+					return fmt.Errorf("line %d: %v", value.Content[j].Line, err)
+				}
+				vars := yamlScalars(value.Content[j+1])
+				for _, host := range hosts {
+					inv.addHost(name, host, vars)
+				}
+			}
+		case "vars":
+			for k, v := range yamlScalars(value) {
+				inv.group(name).vars[k] = v
+			}
+		case "children":
+			if value.Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				child := value.Content[j].Value
+				inv.addChild(name, child)
+				if err := inv.parseYAMLGroup(child, value.Content[j+1], depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// yamlScalars returns the scalar values of a mapping node. Lists and mappings, which
+// none of the variables gossh reads can be, are left out.
+func yamlScalars(node *yaml.Node) map[string]string {
+	vars := make(map[string]string)
+	if node.Kind != yaml.MappingNode {
+		return vars
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i+1].Kind == yaml.ScalarNode {
+			vars[node.Content[i].Value] = node.Content[i+1].Value
+		}
+	}
+	return vars
+}
+
+// resolve returns the hosts of the inventory with their groups and variables.
+func (inv *ansibleInventory) resolve() []AnsibleHost {
+	parents := make(map[string][]string)
+	for _, name := range inv.groupOrder {
+		for _, child := range inv.groups[name].children {
+			parents[child] = append(parents[child], name)
+		}
+	}
+	depths := make(map[string]int)
+	var depth func(name string, seen map[string]bool) int
+	depth = func(name string, seen map[string]bool) int {
+		if d, ok := depths[name]; ok {
+			return d
+		}
+		if name == "all" || seen[name] {
+			return 0
+		}
+		seen[name] = true
+		d := 1
+		for _, p := range parents[name] {
+			if pd := depth(p, seen) + 1; pd > d {
+				d = pd
+			}
+		}
+		depths[name] = d
+		return d
+	}
+
+	var hosts []AnsibleHost
+	for _, host := range inv.hosts {
+		var groups []string
+		member := make(map[string]bool)
+		for _, name := range inv.groupOrder {
+			for _, h := range inv.groups[name].hosts {
+				if h == host && !member[name] {
+					member[name] = true
+					groups = append(groups, name)
+				}
+			}
+		}
+		for i := 0; i < len(groups); i++ {
+			for _, p := range parents[groups[i]] {
+				if !member[p] {
+					member[p] = true
+					groups = append(groups, p)
+				}
+			}
+		}
+
+		ordered := append([]string(nil), groups...)
+		sort.SliceStable(ordered, func(i, j int) bool {
+			di, dj := depth(ordered[i], map[string]bool{}), depth(ordered[j], map[string]bool{})
+			if di != dj {
+				return di < dj
+			}
+			return ordered[i] < ordered[j]
+		})
+		vars := make(map[string]string)
+		if all, ok := inv.groups["all"]; ok {
+			for k, v := range all.vars {
+				vars[k] = v
+			}
+		}
+		for _, name := range ordered {
+			for k, v := range inv.groups[name].vars {
+				vars[k] = v
+			}
+		}
+		for k, v := range inv.hostVars[host] {
+			vars[k] = v
+		}
+
+		h := AnsibleHost{Name: host, Vars: vars}
+		for _, name := range groups {
+			if name != "all" && name != "ungrouped" {
+				h.Groups = append(h.Groups, name)
+			}
+		}
+		hosts = append(hosts, h)
+	}
+	return hosts
+}
+
+// Connection returns a connection for the host from its ansible_host, ansible_user,
+// ansible_port and ansible_ssh_private_key_file variables, or their older ansible_ssh_
+// forms. Like Ansible, it falls back to the host's name; the user and port are left
+// empty when the inventory does not set them, so that they are inherited.
+func (h AnsibleHost) Connection() (Connection, error) {
+	conn := Connection{Name: h.Name, Host: h.Name}
+	if v := h.variable("ansible_host", "ansible_ssh_host"); v != "" {
+		conn.Host = v
+	}
+	conn.User = h.variable("ansible_user", "ansible_ssh_user")
+	if v := h.variable("ansible_port", "ansible_ssh_port"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil || port < 1 || port > 65535 {
+			return conn, fmt.Errorf("host '%s': invalid port '%s'", h.Name, v)
+		}
+		conn.Port = port
+	}
+	if v := h.variable("ansible_ssh_private_key_file", "ansible_private_key_file"); v != "" {
+		conn.KeyPath = expandHome(v)
+	}
+	return conn, nil
+}
+
+// variable returns the value of the first of names that is set.
+func (h AnsibleHost) variable(names ...string) string {
+	for _, name := range names {
+		if v, ok := h.Vars[name]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// maxAnsibleRangeHosts bounds the number of hosts a single host pattern expands to, so
+// that patterns with several ranges cannot multiply into millions of hosts.
+const maxAnsibleRangeHosts = 100000
+
+// expandAnsibleRange expands the ranges in a host pattern such as web[01:03].example.com
+// or db-[a:c], with an optional step as in [1:9:2]. Numbers keep the width of the
+// start when it has leading zeros.
+func expandAnsibleRange(pattern string) ([]string, error) {
+	start := strings.Index(pattern, "[")
+	if start < 0 {
+		return []string{pattern}, nil
+	}
+	end := strings.Index(pattern[start:], "]")
+	if end < 0 {
+		return nil, fmt.Errorf("invalid host range in '%s'", pattern)
+	}
+	end += start
+	prefix, spec, suffix := pattern[:start], pattern[start+1:end], pattern[end+1:]
+
+	bounds := strings.Split(spec, ":")
+	if len(bounds) < 2 || len(bounds) > 3 {
+		return nil, fmt.Errorf("invalid host range in '%s'", pattern)
+	}
+	step := 1
+	if len(bounds) == 3 {
+		var err error
+		if step, err = strconv.Atoi(bounds[2]); err != nil || step < 1 {
+			return nil, fmt.Errorf("invalid host range step in '%s'", pattern)
+		}
+	}
+
+	var values []string
+	from, err1 := strconv.Atoi(bounds[0])
+	to, err2 := strconv.Atoi(bounds[1])
+	switch {
+	case err1 == nil && err2 == nil:
+		if from > to || to-from >= maxAnsibleRangeHosts {
+			return nil, fmt.Errorf("invalid host range in '%s'", pattern)
+		}
+		width := 0
+		if len(bounds[0]) > 1 && bounds[0][0] == '0' {
+			width = len(bounds[0])
+		}
+		for n := from; n <= to; n += step {
+			values = append(values, fmt.Sprintf("%0*d", width, n))
+		}
+	case len(bounds[0]) == 1 && len(bounds[1]) == 1 && bounds[0] <= bounds[1]:
+		for c := bounds[0][0]; c <= bounds[1][0]; c += byte(step) {
+			values = append(values, string(c))
+			if int(c)+step > 255 {
+				break
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid host range in '%s'", pattern)
+	}
+
+	rest, err := expandAnsibleRange(suffix)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)*len(rest) > maxAnsibleRangeHosts {
+		return nil, fmt.Errorf("host range in '%s' expands to more than %d hosts", pattern, maxAnsibleRangeHosts)
+	}
+	var hosts []string
+	for _, v := range values {
+		for _, r := range rest {
+			hosts = append(hosts, prefix+v+r)
+		}
+	}
+	return hosts, nil
+}
+
+// splitAnsibleArgs splits a host line of an INI inventory into the host pattern and
+// its variables. Like the shell, and Ansible, single or double quotes keep spaces in a
+// value and are removed.
+func splitAnsibleArgs(line string) []string {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// unquoteAnsible removes single or double quotes around an INI value.
+func unquoteAnsible(value string) string {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	return unquote(value)
+}
@@ -0,0 +1,266 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandAnsibleRange(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+		wantErr bool
+	}{
+		{"web.example.com", []string{"web.example.com"}, false},
+		{"web[1:3]", []string{"web1", "web2", "web3"}, false},
+		{"web[01:03].example.com", []string{"web01.example.com", "web02.example.com", "web03.example.com"}, false},
+		{"web[08:11]", []string{"web08", "web09", "web10", "web11"}, false},
+		{"web[001:2]", []string{"web001", "web002"}, false},
+		{"web[1:10:3]", []string{"web1", "web4", "web7", "web10"}, false},
+		{"web[00:10:5]", []string{"web00", "web05", "web10"}, false},
+		{"web[1:2:5]", []string{"web1"}, false},
+		{"db-[a:c]", []string{"db-a", "db-b", "db-c"}, false},
+		{"db-[a:e:2]", []string{"db-a", "db-c", "db-e"}, false},
+		{"r[1:2]-[a:b]", []string{"r1-a", "r1-b", "r2-a", "r2-b"}, false},
+		{"web[3:1]", nil, true},
+		{"web[1:3", nil, true},
+		{"web[1]", nil, true},
+		{"web[1:2:3:4]", nil, true},
+		{"web[1:3:0]", nil, true},
+		{"web[1:3:x]", nil, true},
+		{"web[a:3]", nil, true},
+		{"web[c:a]", nil, true},
+		{"web[0:100000]", nil, true},
+		// Ranges multiply, so the total is bounded as well.
+		{"h[0:99999][0:99999]", nil, true},
+		{"h[0:999][0:999]", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := expandAnsibleRange(tt.pattern)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("expandAnsibleRange(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandAnsibleRange(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestExpandAnsibleRangeLimit(t *testing.T) {
+	hosts, err := expandAnsibleRange("h[0:99][0:999]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != maxAnsibleRangeHosts {
+		t.Errorf("got %d hosts, want %d", len(hosts), maxAnsibleRangeHosts)
+	}
+}
+
+// writeInventory writes an inventory file with the given name to a temporary
+// directory and returns its path.
+func writeInventory(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(strings.TrimLeft(content, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseAnsibleInventoryINI(t *testing.T) {
+	path := writeInventory(t, "hosts", `
+# Hosts before the first section are ungrouped.
+lone ansible_host=10.0.0.9
+
+[all:vars]
+ansible_user=alluser
+ansible_port=2000
+
+[web]
+web[01:02].example.com
+db1 ansible_user='host user'
+alt:2222
+
+[web:vars]
+ansible_user=webuser
+
+[prod:children]
+web
+
+[prod:vars]
+ansible_user=produser
+ansible_port=2200
+ansible_ssh_private_key_file=/keys/prod
+
+; Groups at the same depth apply in name order.
+[b]
+db1
+[b:vars]
+role=b
+
+[a]
+db1
+[a:vars]
+role=a
+`)
+	hosts, err := ParseAnsibleInventory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []AnsibleHost{
+		{
+			Name: "lone",
+			Vars: map[string]string{"ansible_host": "10.0.0.9", "ansible_user": "alluser", "ansible_port": "2000"},
+		},
+		{
+			Name:   "web01.example.com",
+			Groups: []string{"web", "prod"},
+			Vars:   map[string]string{"ansible_user": "webuser", "ansible_port": "2200", "ansible_ssh_private_key_file": "/keys/prod"},
+		},
+		{
+			Name:   "web02.example.com",
+			Groups: []string{"web", "prod"},
+			Vars:   map[string]string{"ansible_user": "webuser", "ansible_port": "2200", "ansible_ssh_private_key_file": "/keys/prod"},
+		},
+		{
+			Name:   "db1",
+			Groups: []string{"web", "b", "a", "prod"},
+			Vars:   map[string]string{"ansible_user": "host user", "ansible_port": "2200", "ansible_ssh_private_key_file": "/keys/prod", "role": "b"},
+		},
+		{
+			Name:   "alt",
+			Groups: []string{"web", "prod"},
+			Vars:   map[string]string{"ansible_user": "webuser", "ansible_port": "2222", "ansible_ssh_private_key_file": "/keys/prod"},
+		},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("ParseAnsibleInventory:\n got %+v\nwant %+v", hosts, want)
+	}
+}
+
+func TestParseAnsibleInventoryYAML(t *testing.T) {
+	path := writeInventory(t, "hosts.yml", `
+all:
+  vars:
+    ansible_user: alluser
+  children:
+    prod:
+      vars:
+        ansible_user: produser
+        ansible_port: 2200
+      children:
+        web:
+          hosts:
+            web[1:3:2]:
+              ansible_port: 2222
+          vars:
+            ansible_user: webuser
+        db:
+          hosts:
+            db1:
+    ungrouped:
+      hosts:
+        lone:
+`)
+	hosts, err := ParseAnsibleInventory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []AnsibleHost{
+		{Name: "web1", Groups: []string{"web", "prod"}, Vars: map[string]string{"ansible_user": "webuser", "ansible_port": "2222"}},
+		{Name: "web3", Groups: []string{"web", "prod"}, Vars: map[string]string{"ansible_user": "webuser", "ansible_port": "2222"}},
+		{Name: "db1", Groups: []string{"db", "prod"}, Vars: map[string]string{"ansible_user": "produser", "ansible_port": "2200"}},
+		{Name: "lone", Vars: map[string]string{"ansible_user": "alluser"}},
+	}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("ParseAnsibleInventory:\n got %+v\nwant %+v", hosts, want)
+	}
+}
+
+func TestParseAnsibleInventoryErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"section", "hosts", "[web:hosts]\nweb1\n", "line 1: invalid section [web:hosts]"},
+		{"vars", "hosts", "[web:vars]\nansible_user\n", "line 2: expected key=value"},
+		{"host vars", "hosts", "[web]\nweb1 ansible_user\n", "line 2: expected key=value after host"},
+		{"ini range", "hosts", "[web]\n\nweb[3:1]\n", "line 3: invalid host range"},
+		{"yaml range", "hosts.yml", "web:\n  hosts:\n    web[1:\n", "line 3: invalid host range"},
+		{"yaml groups", "hosts.yaml", "- web\n", "expected a mapping of groups"},
+	}
+	for _, tt := range tests {
+		_, err := ParseAnsibleInventory(writeInventory(t, tt.file, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want it to contain %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestAnsibleHostConnection(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    AnsibleHost
+		want    Connection
+		wantErr bool
+	}{
+		{
+			name: "name only",
+			host: AnsibleHost{Name: "web1"},
+			want: Connection{Name: "web1", Host: "web1"},
+		},
+		{
+			name: "all variables",
+			host: AnsibleHost{Name: "web1", Vars: map[string]string{
+				"ansible_host":                 "10.0.0.1",
+				"ansible_user":                 "deploy",
+				"ansible_port":                 "2222",
+				"ansible_ssh_private_key_file": "/keys/web",
+			}},
+			want: Connection{Name: "web1", Host: "10.0.0.1", User: "deploy", Port: 2222, KeyPath: "/keys/web"},
+		},
+		{
+			name: "older names",
+			host: AnsibleHost{Name: "web1", Vars: map[string]string{
+				"ansible_ssh_host":         "10.0.0.1",
+				"ansible_ssh_user":         "deploy",
+				"ansible_ssh_port":         "2222",
+				"ansible_private_key_file": "/keys/web",
+			}},
+			want: Connection{Name: "web1", Host: "10.0.0.1", User: "deploy", Port: 2222, KeyPath: "/keys/web"},
+		},
+		{
+			name: "new names win",
+			host: AnsibleHost{Name: "web1", Vars: map[string]string{"ansible_user": "new", "ansible_ssh_user": "old"}},
+			want: Connection{Name: "web1", Host: "web1", User: "new"},
+		},
+		{
+			name:    "invalid port",
+			host:    AnsibleHost{Name: "web1", Vars: map[string]string{"ansible_port": "ssh"}},
+			wantErr: true,
+		},
+		{
+			name:    "port out of range",
+			host:    AnsibleHost{Name: "web1", Vars: map[string]string{"ansible_port": "70000"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.host.Connection()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Connection() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// bundleFormat marks an export encrypted with a passphrase.
+const bundleFormat = "gossh-encrypted-export"
+
+// bundleVersion is the version of the bundle layout written by SealBundle.
+const bundleVersion = 1
+
+// Argon2id parameters for new bundles. They are stored in the bundle, so they can be
+// raised later without breaking existing bundles.
+const (
+	argon2Time    = 3
+	argon2Memory  = 64 * 1024 // KiB
+	argon2Threads = 4
+
+	// argon2MaxMemory and argon2MaxTime bound the memory and the number of passes a
+	// bundle can ask for, so that opening a crafted file cannot exhaust the machine
+	// or keep it busy for hours.
+	argon2MaxMemory = 4 * 1024 * 1024 // KiB
+	argon2MaxTime   = 32
+)
+
+// ErrWrongPassphrase is returned by OpenBundle when the passphrase does not decrypt the
+// bundle, or the bundle was modified.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted bundle")
+
+// bundle is the JSON envelope of an encrypted export. The key is derived from the
+// passphrase with Argon2id and the content is sealed with AES-256-GCM.
+type bundle struct {
+	Format     string `json:"format"`
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Time       uint32 `json:"time"`
+	Memory     uint32 `json:"memory"`
+	Threads    uint8  `json:"threads"`
+	Salt       string `json:"salt"`
+	Nonce      string `json:"nonce"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// SealBundle encrypts data with a key derived from passphrase and returns the bundle.
+func SealBundle(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	b := bundle{
+		Format:  bundleFormat,
+		Version: bundleVersion,
+		KDF:     "argon2id",
+		Time:    argon2Time,
+		Memory:  argon2Memory,
+		Threads: argon2Threads,
+		Salt:    base64.StdEncoding.EncodeToString(salt),
+	}
+
+	gcm, err := bundleCipher(b, salt, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	b.Nonce = base64.StdEncoding.EncodeToString(nonce)
+	b.Ciphertext = base64.StdEncoding.EncodeToString(gcm.Seal(nil, nonce, data, nil))
+	return json.MarshalIndent(b, "", "  ")
+}
+
+// IsBundle reports whether data is an encrypted export.
+func IsBundle(data []byte) bool {
+	var b bundle
+	return json.Unmarshal(data, &b) == nil && b.Format == bundleFormat
+}
+
+// OpenBundle decrypts a bundle created by SealBundle.
+func OpenBundle(data []byte, passphrase string) ([]byte, error) {
+	var b bundle
+	if err := json.Unmarshal(data, &b); err != nil || b.Format != bundleFormat {
+		return nil, fmt.Errorf("not an encrypted gossh export")
+	}
+	if b.Version > bundleVersion {
+		return nil, fmt.Errorf("bundle version %d is newer than this gossh supports (%d)", b.Version, bundleVersion)
+	}
+	if b.KDF != "argon2id" {
+		return nil, fmt.Errorf("unsupported key derivation function '%s'", b.KDF)
+	}
+	salt, err1 := base64.StdEncoding.DecodeString(b.Salt)
+	nonce, err2 := base64.StdEncoding.DecodeString(b.Nonce)
+	ciphertext, err3 := base64.StdEncoding.DecodeString(b.Ciphertext)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return nil, fmt.Errorf("corrupted bundle: %w", err)
+	}
+
+	gcm, err := bundleCipher(b, salt, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("corrupted bundle: bad nonce length")
+	}
+	data, err = gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return data, nil
+}
+
+func bundleCipher(b bundle, salt []byte, passphrase string) (cipher.AEAD, error) {
+	if b.Time == 0 || b.Time > argon2MaxTime || b.Memory == 0 || b.Memory > argon2MaxMemory || b.Threads == 0 {
+		return nil, fmt.Errorf("corrupted bundle: invalid key derivation parameters")
+	}
+	key := argon2.IDKey([]byte(passphrase), salt, b.Time, b.Memory, b.Threads, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -1,97 +1,145 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"strings"
 )
 
 type Connection struct {
-	Name            string `json:"name"`
-	Group           string `json:"group,omitempty"`
-	User            string `json:"user"`
-	Host            string `json:"host"`
-	Port            int    `json:"port"`
-	KeyPath         string `json:"key_path,omitempty"`
-	CredentialAlias string `json:"credential_alias,omitempty"`
-}
-
-var configFilePath string
-
-func init() {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Println("Error getting user home directory:", err)
-		os.Exit(1)
-	}
-	configDir := filepath.Join(home, ".config", "gossh")
-	if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		os.MkdirAll(configDir, 0755)
-	}
-	configFilePath = filepath.Join(configDir, "config.json")
+	Name            string   `json:"name" yaml:"name" toml:"name"`
+	Group           string   `json:"group,omitempty" yaml:"group,omitempty" toml:"group,omitempty"`
+	User            string   `json:"user,omitempty" yaml:"user,omitempty" toml:"user,omitempty"`
+	Host            string   `json:"host" yaml:"host" toml:"host"`
+	Port            int      `json:"port,omitempty" yaml:"port,omitempty" toml:"port,omitzero"`
+	KeyPath         string   `json:"key_path,omitempty" yaml:"key_path,omitempty" toml:"key_path,omitempty"`
+	CredentialAlias string   `json:"credential_alias,omitempty" yaml:"credential_alias,omitempty" toml:"credential_alias,omitempty"`
+	BandwidthLimit  string   `json:"bandwidth_limit,omitempty" yaml:"bandwidth_limit,omitempty" toml:"bandwidth_limit,omitempty"`
+	ControlPersist  string   `json:"control_persist,omitempty" yaml:"control_persist,omitempty" toml:"control_persist,omitempty"`
+	ProxyJump       string   `json:"proxy_jump,omitempty" yaml:"proxy_jump,omitempty" toml:"proxy_jump,omitempty"`
+	Tags            []string `json:"tags,omitempty" yaml:"tags,omitempty" toml:"tags,omitempty"`
 }
 
+// LoadConnections returns the saved connections with the settings they inherit from
+// their group and the global defaults filled in. Use LoadDocument to get the
+// connections as they are stored.
 func LoadConnections() ([]Connection, error) {
-	var connections []Connection
-	data, err := os.ReadFile(configFilePath)
+	doc, err := LoadDocument()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return connections, nil
-		}
 		return nil, err
 	}
-	if len(data) == 0 {
-		return connections, nil
+	connections := make([]Connection, len(doc.Connections))
+	for i, c := range doc.Connections {
+		connections[i] = doc.Resolve(c).Connection
 	}
-
-	err = json.Unmarshal(data, &connections)
-	return connections, err
+	return connections, nil
 }
 
+// SaveConnections replaces all saved connections.
 func SaveConnections(connections []Connection) error {
-	data, err := json.MarshalIndent(connections, "", "  ")
-	if err != nil {
-		return err
-	}
-	return os.WriteFile(configFilePath, data, 0644)
+	return ModifyDocument(func(doc *Document) error {
+		doc.Connections = connections
+		return nil
+	})
+}
+
+// ModifyConnections loads the saved connections, passes them to fn and saves the
+// result, holding the configuration lock throughout so that changes made by other
+// gossh processes in the meantime are not lost.
+func ModifyConnections(fn func([]Connection) ([]Connection, error)) error {
+	return ModifyDocument(func(doc *Document) error {
+		connections, err := fn(doc.Connections)
+		if err != nil {
+			return err
+		}
+		doc.Connections = connections
+		return nil
+	})
 }
 
 func AddConnection(conn Connection) error {
-	connections, err := LoadConnections()
-	if err != nil {
-		return err
-	}
+	return ModifyConnections(func(connections []Connection) ([]Connection, error) {
+		for _, c := range connections {
+			if c.Name == conn.Name {
+				return nil, fmt.Errorf("connection with name '%s' already exists", conn.Name)
+			}
+		}
+		return append(connections, conn), nil
+	})
+}
 
-	for _, c := range connections {
-		if c.Name == conn.Name {
-			return fmt.Errorf("connection with name '%s' already exists", conn.Name)
+func RemoveConnection(name string) error {
+	return ModifyConnections(func(connections []Connection) ([]Connection, error) {
+		var newConnections []Connection
+		var found bool
+		for _, c := range connections {
+			if c.Name == name {
+				found = true
+				continue
+			}
+			newConnections = append(newConnections, c)
 		}
-	}
 
-	connections = append(connections, conn)
-	return SaveConnections(connections)
+		if !found {
+			return nil, fmt.Errorf("connection with name '%s' not found", name)
+		}
+		return newConnections, nil
+	})
 }
 
-func RemoveConnection(name string) error {
-	connections, err := LoadConnections()
-	if err != nil {
-		return err
-	}
+// UpdateConnection replaces the settings of the connection with the given name. The
+// name itself cannot be changed this way; use RenameConnection.
+func UpdateConnection(conn Connection) error {
+	return ModifyConnections(func(connections []Connection) ([]Connection, error) {
+		for i, c := range connections {
+			if c.Name == conn.Name {
+				connections[i] = conn
+				return connections, nil
+			}
+		}
+		return nil, fmt.Errorf("connection with name '%s' not found", conn.Name)
+	})
+}
 
-	var newConnections []Connection
-	var found bool
-	for _, c := range connections {
-		if c.Name == name {
-			found = true
-			continue
+// RenameConnection renames a connection and updates the jump host settings of other
+// connections and of the defaults that refer to it.
+func RenameConnection(oldName, newName string) error {
+	return ModifyDocument(func(doc *Document) error {
+		index := -1
+		for i, c := range doc.Connections {
+			switch c.Name {
+			case newName:
+				return fmt.Errorf("connection with name '%s' already exists", newName)
+			case oldName:
+				index = i
+			}
+		}
+		if index < 0 {
+			return fmt.Errorf("connection with name '%s' not found", oldName)
 		}
-		newConnections = append(newConnections, c)
-	}
 
-	if !found {
-		return fmt.Errorf("connection with name '%s' not found", name)
-	}
+		doc.Connections[index].Name = newName
+		for i := range doc.Connections {
+			doc.Connections[i].ProxyJump = RenameJumpHost(doc.Connections[i].ProxyJump, oldName, newName)
+		}
+		doc.Defaults.ProxyJump = RenameJumpHost(doc.Defaults.ProxyJump, oldName, newName)
+		for group, d := range doc.Groups {
+			d.ProxyJump = RenameJumpHost(d.ProxyJump, oldName, newName)
+			doc.Groups[group] = d
+		}
+		return nil
+	})
+}
 
-	return SaveConnections(newConnections)
+// RenameJumpHost replaces oldName in a comma-separated jump host list.
+func RenameJumpHost(proxyJump, oldName, newName string) string {
+	if proxyJump == "" {
+		return proxyJump
+	}
+	hops := strings.Split(proxyJump, ",")
+	for i, hop := range hops {
+		if strings.TrimSpace(hop) == oldName {
+			hops[i] = newName
+		}
+	}
+	return strings.Join(hops, ",")
 }
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 )
 
 type Credential struct {
@@ -12,18 +11,6 @@ type Credential struct {
 	Password string `json:"password"`
 }
 
-var credentialsFilePath string
-
-func init() {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Println("Error getting user home directory:", err)
-		os.Exit(1)
-	}
-	configDir := filepath.Join(home, ".config", "gossh")
-	credentialsFilePath = filepath.Join(configDir, "credentials.json")
-}
-
 func LoadCredentials() ([]Credential, error) {
 	var credentials []Credential
 	data, err := os.ReadFile(credentialsFilePath)
@@ -54,7 +41,14 @@ func LoadCredentials() ([]Credential, error) {
 	return credentials, nil
 }
 
+// SaveCredentials encrypts and replaces all saved credentials.
 func SaveCredentials(credentials []Credential) error {
+	return withLock(func() error {
+		return saveCredentials(credentials)
+	})
+}
+
+func saveCredentials(credentials []Credential) error {
 	credsToSave := make([]Credential, len(credentials))
 	copy(credsToSave, credentials)
 
@@ -72,44 +66,51 @@ func SaveCredentials(credentials []Credential) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(credentialsFilePath, data, 0600)
+	return writeFileAtomic(credentialsFilePath, data, 0600)
 }
 
-func AddCredential(cred Credential) error {
-	credentials, err := LoadCredentials()
-	if err != nil {
-		return err
-	}
-
-	for _, c := range credentials {
-		if c.Alias == cred.Alias {
-			return fmt.Errorf("credential with alias '%s' already exists", cred.Alias)
+// ModifyCredentials loads the saved credentials, passes them to fn and saves the
+// result while holding the configuration lock.
+func ModifyCredentials(fn func([]Credential) ([]Credential, error)) error {
+	return withLock(func() error {
+		credentials, err := LoadCredentials()
+		if err != nil {
+			return err
 		}
-	}
+		credentials, err = fn(credentials)
+		if err != nil {
+			return err
+		}
+		return saveCredentials(credentials)
+	})
+}
 
-	credentials = append(credentials, cred)
-	return SaveCredentials(credentials)
+func AddCredential(cred Credential) error {
+	return ModifyCredentials(func(credentials []Credential) ([]Credential, error) {
+		for _, c := range credentials {
+			if c.Alias == cred.Alias {
+				return nil, fmt.Errorf("credential with alias '%s' already exists", cred.Alias)
+			}
+		}
+		return append(credentials, cred), nil
+	})
 }
 
 func RemoveCredential(alias string) error {
-	credentials, err := LoadCredentials()
-	if err != nil {
-		return err
-	}
-
-	var newCredentials []Credential
-	var found bool
-	for _, c := range credentials {
-		if c.Alias == alias {
-			found = true
-			continue
+	return ModifyCredentials(func(credentials []Credential) ([]Credential, error) {
+		var newCredentials []Credential
+		var found bool
+		for _, c := range credentials {
+			if c.Alias == alias {
+				found = true
+				continue
+			}
+			newCredentials = append(newCredentials, c)
 		}
-		newCredentials = append(newCredentials, c)
-	}
 
-	if !found {
-		return fmt.Errorf("credential with alias '%s' not found", alias)
-	}
-
-	return SaveCredentials(newCredentials)
+		if !found {
+			return nil, fmt.Errorf("credential with alias '%s' not found", alias)
+		}
+		return newCredentials, nil
+	})
 }
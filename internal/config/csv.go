@@ -0,0 +1,168 @@
+package config
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CSVColumns are the columns of a connection spreadsheet, named like the fields of
+// the configuration file. Tags are separated by commas within their cell.
+var CSVColumns = []string{
+	"name", "group", "user", "host", "port", "key_path", "credential_alias",
+	"bandwidth_limit", "control_persist", "proxy_jump", "tags",
+}
+
+// CSVRow is a row of a connection spreadsheet. Err is set when the row is invalid, in
+// which case Connection holds what could be read.
+type CSVRow struct {
+	Line       int
+	Connection Connection
+	Err        error
+}
+
+// WriteConnectionsCSV writes connections as CSV with a header row.
+func WriteConnectionsCSV(w io.Writer, connections []Connection) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVColumns); err != nil {
+		return err
+	}
+	for _, c := range connections {
+		port := ""
+		if c.Port != 0 {
+			port = strconv.Itoa(c.Port)
+		}
+		record := []string{
+			c.Name, c.Group, c.User, c.Host, port, c.KeyPath, c.CredentialAlias,
+			c.BandwidthLimit, c.ControlPersist, c.ProxyJump, strings.Join(c.Tags, ","),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadConnectionsCSV reads a connection spreadsheet. The first row names the columns,
+// in any order and case; only name and host are required. Each following row becomes
+// a connection, or an error for that row, so that one bad row does not keep the
+// others from being read. An error is returned only if the header is unusable.
+func ReadConnectionsCSV(r io.Reader) ([]CSVRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("the file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(CSVColumns, name) {
+			return nil, fmt.Errorf("line 1: unknown column '%s'; the columns are %s", name, strings.Join(CSVColumns, ", "))
+		}
+		if _, dup := columns[name]; dup {
+			return nil, fmt.Errorf("line 1: column '%s' appears twice", name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"name", "host"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("line 1: the '%s' column is missing", required)
+		}
+	}
+
+	var rows []CSVRow
+	seen := make(map[string]int)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, CSVRow{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+		if err != nil {
+			return rows, err
+		}
+		line, _ := cr.FieldPos(0)
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		row := CSVRow{Line: line}
+		if len(record) != len(header) {
+			row.Err = fmt.Errorf("expected %d fields, found %d", len(header), len(record))
+			rows = append(rows, row)
+			continue
+		}
+		row.Connection, row.Err = csvConnection(record, columns)
+		if row.Err == nil && row.Connection.Name != "" {
+			if first, dup := seen[row.Connection.Name]; dup {
+				row.Err = fmt.Errorf("connection '%s' is already defined on line %d", row.Connection.Name, first)
+			} else {
+				seen[row.Connection.Name] = line
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// csvConnection converts a record into a connection, checking the values that can be
+// checked without the rest of the configuration.
+func csvConnection(record []string, columns map[string]int) (Connection, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	c := Connection{
+		Name:            field("name"),
+		Group:           field("group"),
+		User:            field("user"),
+		Host:            field("host"),
+		KeyPath:         field("key_path"),
+		CredentialAlias: field("credential_alias"),
+		BandwidthLimit:  field("bandwidth_limit"),
+		ControlPersist:  field("control_persist"),
+		ProxyJump:       field("proxy_jump"),
+	}
+	if c.Name == "" {
+		return c, fmt.Errorf("the name is missing")
+	}
+	if c.Host == "" {
+		return c, fmt.Errorf("the host is missing")
+	}
+	if port := field("port"); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return c, fmt.Errorf("invalid port '%s'; it must be between 1 and 65535", port)
+		}
+		c.Port = n
+	}
+	if c.KeyPath != "" && c.CredentialAlias != "" {
+		return c, fmt.Errorf("key_path and credential_alias cannot both be set")
+	}
+	for _, tag := range strings.Split(field("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag == "" {
+			continue
+		}
+		if err := ValidateTag(tag); err != nil {
+			return c, err
+		}
+		c.Tags = append(c.Tags, tag)
+	}
+	return c, nil
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConnectionsCSVRoundTrip(t *testing.T) {
+	connections := []Connection{
+		{Name: "web1", Group: "web", User: "deploy", Host: "10.0.0.1", Port: 2222, KeyPath: "/keys/web", Tags: []string{"env=prod", "canary"}},
+		{Name: "db1", User: "admin", Host: "db.example.com", CredentialAlias: "db", BandwidthLimit: "10MB/s", ControlPersist: "10m", ProxyJump: "web1"},
+		{Name: "bare", Host: "bare.example.com"},
+	}
+	var buf bytes.Buffer
+	if err := WriteConnectionsCSV(&buf, connections); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := ReadConnectionsCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(connections) {
+		t.Fatalf("read %d rows, want %d", len(rows), len(connections))
+	}
+	for i, row := range rows {
+		if row.Err != nil {
+			t.Errorf("row %d: %v", i, row.Err)
+		}
+		if row.Line != i+2 {
+			t.Errorf("row %d: line = %d, want %d", i, row.Line, i+2)
+		}
+		if !reflect.DeepEqual(row.Connection, connections[i]) {
+			t.Errorf("row %d:\n got %+v\nwant %+v", i, row.Connection, connections[i])
+		}
+	}
+}
+
+func TestReadConnectionsCSVHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{"minimal", "name,host\n", ""},
+		{"any order and case", "Host, NAME ,Port\n", ""},
+		{"byte order mark", "\ufeffname,host\n", ""},
+		{"empty file", "", "the file is empty"},
+		{"unknown column", "name,host,address\n", "line 1: unknown column 'address'"},
+		{"duplicate column", "name,host,Name\n", "line 1: column 'name' appears twice"},
+		{"missing name", "host,user\n", "line 1: the 'name' column is missing"},
+		{"missing host", "name,user\n", "line 1: the 'host' column is missing"},
+	}
+	for _, tt := range tests {
+		_, err := ReadConnectionsCSV(strings.NewReader(tt.input))
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v, want it to contain %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReadConnectionsCSVRows(t *testing.T) {
+	input := "\ufeffName,Host,Port,User,Tags,Key_Path,Credential_Alias\n" +
+		"web1,10.0.0.1,22,deploy,\"env=prod, canary\",,\n" + // line 2
+		"\n" + // blank lines are skipped
+		"web2,,,,,,\n" + // line 4
+		",10.0.0.3,,,,,\n" + // line 5
+		"web4,10.0.0.4,0,,,,\n" + // line 6
+		"web5,10.0.0.5,ssh,,,,\n" + // line 7
+		"web6,10.0.0.6,,,,/keys/web,db\n" + // line 8
+		"web7,10.0.0.7,,,\"bad tag\",,\n" + // line 9
+		"web8,10.0.0.8\n" + // line 10
+		"web1,10.0.0.9,,,,,\n" + // line 11
+		"\"web9\",\"10.0.0.10\",,,\"env=prod,\n" + // line 12, with a tag cell over two lines
+		"role=web\",,\n" +
+		"web10,10.0.0.11,,,,,\n" + // line 14
+		"web11,\"10.0.0.12\"x,,,,,\n" + // line 15
+		"web12,10.0.0.13,,,,,\n" // line 16
+
+	rows, err := ReadConnectionsCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		line    int
+		name    string
+		wantErr string
+	}{
+		{2, "web1", ""},
+		{4, "web2", "the host is missing"},
+		{5, "", "the name is missing"},
+		{6, "web4", "invalid port '0'"},
+		{7, "web5", "invalid port 'ssh'"},
+		{8, "web6", "key_path and credential_alias cannot both be set"},
+		{9, "web7", "invalid tag 'bad tag'"},
+		{10, "", "expected 7 fields, found 2"},
+		{11, "web1", "connection 'web1' is already defined on line 2"},
+		{12, "web9", ""},
+		{14, "web10", ""},
+		{15, "", "extraneous or missing \" in quoted-field"},
+		{16, "web12", ""},
+	}
+	if len(rows) != len(want) {
+		for _, row := range rows {
+			t.Logf("line %d: %+v %v", row.Line, row.Connection, row.Err)
+		}
+		t.Fatalf("read %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		row := rows[i]
+		if row.Line != w.line {
+			t.Errorf("row %d: line = %d, want %d", i, row.Line, w.line)
+		}
+		if row.Connection.Name != w.name {
+			t.Errorf("line %d: name = %q, want %q", w.line, row.Connection.Name, w.name)
+		}
+		if w.wantErr == "" {
+			if row.Err != nil {
+				t.Errorf("line %d: unexpected error %v", w.line, row.Err)
+			}
+			continue
+		}
+		if row.Err == nil || !strings.Contains(row.Err.Error(), w.wantErr) {
+			t.Errorf("line %d: error = %v, want it to contain %q", w.line, row.Err, w.wantErr)
+		}
+	}
+
+	if got := rows[0].Connection; got.Port != 22 || got.User != "deploy" || !reflect.DeepEqual(got.Tags, []string{"env=prod", "canary"}) {
+		t.Errorf("line 2: connection = %+v", got)
+	}
+	if got := rows[9].Connection.Tags; !reflect.DeepEqual(got, []string{"env=prod", "role=web"}) {
+		t.Errorf("line 12: tags = %v, want [env=prod role=web]", got)
+	}
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// ConfigVersion is the version of the config.json format written by this gossh.
+//
+// Version 1 was a bare JSON array of connections. Version 2 wraps them in a document
+// that also holds settings shared by all connections.
+const ConfigVersion = 2
+
+// Document is the content of config.json.
+type Document struct {
+	Version     int                 `json:"version" yaml:"version" toml:"version"`
+	Defaults    Defaults            `json:"defaults" yaml:"defaults" toml:"defaults"`
+	Groups      map[string]Defaults `json:"groups,omitempty" yaml:"groups,omitempty" toml:"groups,omitempty"`
+	Connections []Connection        `json:"connections" yaml:"connections" toml:"connections"`
+}
+
+// Defaults holds connection settings inherited by the connections that do not set them,
+// either from the global defaults or from the defaults of their group.
+type Defaults struct {
+	User            string `json:"user,omitempty" yaml:"user,omitempty" toml:"user,omitempty"`
+	Port            int    `json:"port,omitempty" yaml:"port,omitempty" toml:"port,omitzero"`
+	KeyPath         string `json:"key_path,omitempty" yaml:"key_path,omitempty" toml:"key_path,omitempty"`
+	CredentialAlias string `json:"credential_alias,omitempty" yaml:"credential_alias,omitempty" toml:"credential_alias,omitempty"`
+	BandwidthLimit  string `json:"bandwidth_limit,omitempty" yaml:"bandwidth_limit,omitempty" toml:"bandwidth_limit,omitempty"`
+	ControlPersist  string `json:"control_persist,omitempty" yaml:"control_persist,omitempty" toml:"control_persist,omitempty"`
+	ProxyJump       string `json:"proxy_jump,omitempty" yaml:"proxy_jump,omitempty" toml:"proxy_jump,omitempty"`
+}
+
+// IsZero reports whether no setting is defined.
+func (d Defaults) IsZero() bool {
+	return d == Defaults{}
+}
+
+// Migration describes the upgrade of config.json to the current format.
+type Migration struct {
+	Path        string
+	From        int
+	To          int
+	Connections int
+	Backup      string
+}
+
+// LoadDocument reads config.json. A file in an older format is upgraded, and the
+// original is kept next to it as config.json.v<N>.bak.
+func LoadDocument() (*Document, error) {
+	doc, version, _, err := readDocument()
+	if err != nil {
+		return nil, err
+	}
+	if version < ConfigVersion {
+		// Writing the upgraded file is best effort; the document read above is usable
+		// either way and the upgrade is retried on the next load.
+		withLock(func() error {
+			migrated, err := migrateDocument()
+			if err == nil {
+				doc = migrated
+			}
+			return err
+		})
+	}
+	return doc, nil
+}
+
+// CheckMigration reports how config.json would be upgraded without changing it. It
+// returns nil if the file is already in the current format.
+func CheckMigration() (*Migration, error) {
+	doc, version, _, err := readDocument()
+	if err != nil {
+		return nil, err
+	}
+	if version == ConfigVersion {
+		return nil, nil
+	}
+	return &Migration{
+		Path:        configFilePath,
+		From:        version,
+		To:          ConfigVersion,
+		Connections: len(doc.Connections),
+		Backup:      migrationBackupPath(version),
+	}, nil
+}
+
+// Migrate upgrades config.json to the current format, keeping a backup of the
+// original. It returns nil if the file was already current.
+func Migrate() (*Migration, error) {
+	var m *Migration
+	err := withLock(func() error {
+		var err error
+		if m, err = CheckMigration(); err != nil || m == nil {
+			return err
+		}
+		_, err = migrateDocument()
+		return err
+	})
+	return m, err
+}
+
+// ModifyDocument loads config.json, passes it to fn and saves the result while holding
+// the configuration lock.
+func ModifyDocument(fn func(*Document) error) error {
+	return withLock(func() error {
+		doc, err := migrateDocument()
+		if err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+		return saveDocument(doc)
+	})
+}
+
+// migrateDocument reads config.json and, if it is in an older format, writes it back in
+// the current one after saving the original as a backup. The lock must be held.
+func migrateDocument() (*Document, error) {
+	doc, version, data, err := readDocument()
+	if err != nil || version == ConfigVersion {
+		return doc, err
+	}
+	if err := replaceFile(migrationBackupPath(version), data, 0644); err != nil {
+		return nil, fmt.Errorf("could not back up %s: %w", configFilePath, err)
+	}
+	if err := saveDocument(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func migrationBackupPath(version int) string {
+	return fmt.Sprintf("%s.v%d.bak", configFilePath, version)
+}
+
+// readDocument reads the configuration file and converts it to the current format in
+// memory. It also returns the version the file was written in and its raw content. A
+// missing or empty file is an empty current document.
+func readDocument() (*Document, int, []byte, error) {
+	doc := &Document{Version: ConfigVersion}
+	data, err := os.ReadFile(configFilePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return doc, ConfigVersion, nil, nil
+		}
+		return nil, 0, nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return doc, ConfigVersion, data, nil
+	}
+	format := formatOf(configFilePath)
+
+	if isListDocument(format, trimmed) {
+		// Version 1: a bare list of connections.
+		if err := unmarshalConfig(format, trimmed, &doc.Connections); err != nil {
+			return nil, 0, nil, fmt.Errorf("%s: %w", configFilePath, err)
+		}
+		return doc, 1, data, nil
+	}
+
+	var header struct {
+		Version int `json:"version" yaml:"version" toml:"version"`
+	}
+	if err := unmarshalConfig(format, trimmed, &header); err != nil {
+		return nil, 0, nil, fmt.Errorf("%s: %w", configFilePath, err)
+	}
+	switch {
+	case header.Version == 0:
+		return nil, 0, nil, fmt.Errorf("%s: missing format version", configFilePath)
+	case header.Version > ConfigVersion:
+		return nil, 0, nil, fmt.Errorf("%s uses format version %d, but this gossh only supports up to version %d; please upgrade gossh", configFilePath, header.Version, ConfigVersion)
+	}
+	if err := unmarshalConfig(format, trimmed, doc); err != nil {
+		return nil, 0, nil, fmt.Errorf("%s: %w", configFilePath, err)
+	}
+	doc.Version = ConfigVersion
+	return doc, header.Version, data, nil
+}
+
+func saveDocument(doc *Document) error {
+	doc.Version = ConfigVersion
+	if doc.Connections == nil {
+		doc.Connections = []Connection{}
+	}
+	previous, _ := os.ReadFile(configFilePath)
+	data, err := marshalDocument(doc, formatOf(configFilePath), previous)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(configFilePath, data, 0644)
+}
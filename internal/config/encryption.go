@@ -7,31 +7,34 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 )
 
-var keyFilePath string
-
-func init() {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Println("Error getting user home directory:", err)
-		os.Exit(1)
-	}
-	configDir := filepath.Join(home, ".config", "gossh")
-	keyFilePath = filepath.Join(configDir, "secret.key")
-}
-
 func getEncryptionKey() ([]byte, error) {
 	if _, err := os.Stat(keyFilePath); os.IsNotExist(err) {
 		key := make([]byte, 32) // AES-256
 		if _, err := rand.Read(key); err != nil {
 			return nil, fmt.Errorf("could not generate encryption key: %w", err)
 		}
-		if err := os.WriteFile(keyFilePath, key, 0600); err != nil {
+		// Create the key exclusively so that a concurrent gossh process that got there
+		// first keeps its key and we use that one instead.
+		f, err := os.OpenFile(keyFilePath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_, err = f.Write(key)
+			if err == nil {
+				err = f.Sync()
+			}
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(keyFilePath)
+				return nil, fmt.Errorf("could not save encryption key: %w", err)
+			}
+			return key, nil
+		}
+		if !os.IsExist(err) {
 			return nil, fmt.Errorf("could not save encryption key: %w", err)
 		}
-		return key, nil
 	}
 	return os.ReadFile(keyFilePath)
 }
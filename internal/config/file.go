@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic replaces the file at path with data without ever leaving a partially
+// written file behind. The data is written to a temporary file in the same directory,
+// flushed to disk and renamed over the original. The previous contents are kept in
+// path + ".bak".
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := backupFile(path, perm); err != nil {
+		return fmt.Errorf("could not back up %s: %w", path, err)
+	}
+	return replaceFile(path, data, perm)
+}
+
+// backupFile copies the current contents of path to path + ".bak", replacing the
+// previous backup. A missing or empty file is not backed up.
+func backupFile(path string, perm os.FileMode) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return replaceFile(path+".bak", data, perm)
+}
+
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir flushes a directory entry change such as a rename to disk. Not every platform
+// supports this, so errors are ignored.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// withLock runs fn while holding an exclusive advisory lock on the configuration
+// directory, so that concurrent gossh processes do not lose each other's changes
+// during a read-modify-write.
+func withLock(fn func() error) error {
+	if err := os.MkdirAll(Dir(), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(Dir(), ".lock"), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open lock file: %w", err)
+	}
+	defer f.Close()
+
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("could not lock configuration: %w", err)
+	}
+	defer unlockFile(f)
+	return fn()
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats the configuration file can be written in.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+// Formats lists the supported configuration file formats.
+var Formats = []string{FormatJSON, FormatYAML, FormatTOML}
+
+// configFileNames are the names the configuration file is looked for under, in order of
+// precedence.
+var configFileNames = []string{"config.yaml", "config.yml", "config.toml", "config.json"}
+
+// findConfigFile returns the configuration file in dir, or config.json if there is none.
+func findConfigFile(dir string) string {
+	for _, name := range configFileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(dir, "config.json")
+}
+
+// ConfigFile returns the path of the configuration file in use.
+func ConfigFile() string {
+	return configFilePath
+}
+
+// formatOf returns the format of a configuration file, judged by its extension.
+func formatOf(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatJSON
+}
+
+func unmarshalConfig(format string, data []byte, v interface{}) error {
+	switch format {
+	case FormatYAML:
+		return yaml.Unmarshal(data, v)
+	case FormatTOML:
+		return toml.Unmarshal(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// isListDocument reports whether a configuration file holds a bare list of
+// connections, the format of version 1.
+func isListDocument(format string, data []byte) bool {
+	switch format {
+	case FormatJSON:
+		return data[0] != '{'
+	case FormatYAML:
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil || len(node.Content) == 0 {
+			return false
+		}
+		return node.Content[0].Kind == yaml.SequenceNode
+	}
+	return false
+}
+
+// marshalDocument encodes a document in the given format. For YAML, the comments and
+// key order of previous, the current content of the file, are carried over.
+func marshalDocument(doc *Document, format string, previous []byte) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return marshalYAML(doc, previous)
+	case FormatTOML:
+		var buf bytes.Buffer
+		enc := toml.NewEncoder(&buf)
+		enc.Indent = ""
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func marshalYAML(doc *Document, previous []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := node.Encode(doc); err != nil {
+		return nil, err
+	}
+	root := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{&node}}
+
+	var old yaml.Node
+	if len(previous) > 0 && yaml.Unmarshal(previous, &old) == nil && len(old.Content) > 0 {
+		root.HeadComment, root.FootComment = old.HeadComment, old.FootComment
+		mergeYAML(old.Content[0], &node)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeYAML copies the comments of old to the corresponding nodes of updated, and
+// orders the keys of updated mappings as they were in old. Keys that are new go last.
+// Connections are matched by name, so comments stay with them when the list changes.
+func mergeYAML(old, updated *yaml.Node) {
+	if old.Kind != updated.Kind {
+		return
+	}
+	updated.HeadComment, updated.LineComment, updated.FootComment = old.HeadComment, old.LineComment, old.FootComment
+
+	switch updated.Kind {
+	case yaml.ScalarNode:
+		if old.Value == updated.Value {
+			updated.Style = old.Style
+		}
+	case yaml.MappingNode:
+		oldIndex := make(map[string]int)
+		for i := 0; i+1 < len(old.Content); i += 2 {
+			oldIndex[old.Content[i].Value] = i
+		}
+		type pair struct {
+			key, value *yaml.Node
+			order      int
+		}
+		var pairs []pair
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			p := pair{updated.Content[i], updated.Content[i+1], len(old.Content) + i}
+			if j, ok := oldIndex[p.key.Value]; ok {
+				mergeYAML(old.Content[j], p.key)
+				mergeYAML(old.Content[j+1], p.value)
+				p.order = j
+			}
+			pairs = append(pairs, p)
+		}
+		// Insertion sort keeps the order stable and the lists are short.
+		for i := 1; i < len(pairs); i++ {
+			for j := i; j > 0 && pairs[j].order < pairs[j-1].order; j-- {
+				pairs[j], pairs[j-1] = pairs[j-1], pairs[j]
+			}
+		}
+		updated.Content = updated.Content[:0]
+		for _, p := range pairs {
+			updated.Content = append(updated.Content, p.key, p.value)
+		}
+	case yaml.SequenceNode:
+		byName := make(map[string]*yaml.Node)
+		for _, item := range old.Content {
+			if name := yamlMappingValue(item, "name"); name != "" {
+				byName[name] = item
+			}
+		}
+		for i, item := range updated.Content {
+			if name := yamlMappingValue(item, "name"); name != "" {
+				if match, ok := byName[name]; ok {
+					mergeYAML(match, item)
+				}
+			} else if i < len(old.Content) {
+				mergeYAML(old.Content[i], item)
+			}
+		}
+	}
+}
+
+// yamlMappingValue returns the scalar value of key in a mapping node, or "".
+func yamlMappingValue(node *yaml.Node, key string) string {
+	if node.Kind != yaml.MappingNode {
+		return ""
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key && node.Content[i+1].Kind == yaml.ScalarNode {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
+
+// Convert writes the configuration in another format and moves the old file aside to
+// <name>.bak. It returns the path of the new file.
+func Convert(format string) (string, error) {
+	var path string
+	err := withLock(func() error {
+		doc, err := migrateDocument()
+		if err != nil {
+			return err
+		}
+		if formatOf(configFilePath) == format {
+			return fmt.Errorf("%s is already in %s format", configFilePath, format)
+		}
+
+		oldPath := configFilePath
+		path = filepath.Join(filepath.Dir(oldPath), "config."+format)
+		data, err := marshalDocument(doc, format, nil)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(path, data, 0644); err != nil {
+			return err
+		}
+		if _, err := os.Stat(oldPath); err == nil {
+			if err := os.Rename(oldPath, oldPath+".bak"); err != nil {
+				return err
+			}
+		}
+		configFilePath = path
+		return nil
+	})
+	return path, err
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile is the profile whose files live directly in the base directory.
+const DefaultProfile = "default"
+
+var (
+	baseDir             string
+	profile             string
+	configFilePath      string
+	credentialsFilePath string
+	keyFilePath         string
+)
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func init() {
+	// An invalid profile name is reported by the commands, through SetBaseDir.
+	SetBaseDir(DefaultBaseDir())
+}
+
+// DefaultBaseDir returns the directory gossh keeps its files in unless told otherwise:
+// $GOSSH_HOME if set, else gossh in $XDG_CONFIG_HOME, else ~/.config/gossh. Earlier
+// versions ignored $XDG_CONFIG_HOME, so ~/.config/gossh is still used when it exists
+// and the directory in $XDG_CONFIG_HOME does not.
+func DefaultBaseDir() string {
+	if dir := os.Getenv("GOSSH_HOME"); dir != "" {
+		return dir
+	}
+	home, homeErr := os.UserHomeDir()
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" && filepath.IsAbs(dir) {
+		dir = filepath.Join(dir, "gossh")
+		if homeErr == nil && !dirExists(dir) && dirExists(filepath.Join(home, ".config", "gossh")) {
+			return filepath.Join(home, ".config", "gossh")
+		}
+		return dir
+	}
+	if homeErr != nil {
+		fmt.Println("Error getting user home directory:", homeErr)
+		os.Exit(1)
+	}
+	return filepath.Join(home, ".config", "gossh")
+}
+
+func dirExists(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// SetBaseDir changes the base directory and selects the profile named by
+// $GOSSH_PROFILE, or else the one last chosen with SetDefaultProfile. If that name is
+// invalid, the default profile is selected and the error is returned.
+func SetBaseDir(dir string) error {
+	baseDir = dir
+	name, source := os.Getenv("GOSSH_PROFILE"), "$GOSSH_PROFILE"
+	if name == "" {
+		name, source = DefaultProfileName(), filepath.Join(baseDir, "profile")
+	}
+	if err := UseProfile(name); err != nil {
+		UseProfile(DefaultProfile)
+		return fmt.Errorf("%s: %w", source, err)
+	}
+	return nil
+}
+
+// BaseDir returns the directory holding all profiles.
+func BaseDir() string {
+	return baseDir
+}
+
+// UseProfile selects the profile whose connections, credentials and encryption key
+// are used by this process.
+func UseProfile(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s'", name)
+	}
+	profile = name
+	dir := Dir()
+	configFilePath = findConfigFile(dir)
+	credentialsFilePath = filepath.Join(dir, "credentials.json")
+	keyFilePath = filepath.Join(dir, "secret.key")
+	return nil
+}
+
+// Profile returns the name of the selected profile.
+func Profile() string {
+	return profile
+}
+
+// Dir returns the directory holding the configuration of the selected profile.
+func Dir() string {
+	return profileDir(profile)
+}
+
+func profileDir(name string) string {
+	if name == DefaultProfile {
+		return baseDir
+	}
+	return filepath.Join(baseDir, "profiles", name)
+}
+
+// DefaultProfileName returns the profile used when none is given on the command line
+// or in $GOSSH_PROFILE.
+func DefaultProfileName() string {
+	data, err := os.ReadFile(filepath.Join(baseDir, "profile"))
+	if err != nil {
+		return DefaultProfile
+	}
+	if name := strings.TrimSpace(string(data)); name != "" {
+		return name
+	}
+	return DefaultProfile
+}
+
+// SetDefaultProfile makes name the profile used by later gossh invocations, creating
+// its directory if needed.
+func SetDefaultProfile(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s'", name)
+	}
+	if err := os.MkdirAll(profileDir(name), 0755); err != nil {
+		return err
+	}
+	return replaceFile(filepath.Join(baseDir, "profile"), []byte(name+"\n"), 0644)
+}
+
+// Profiles returns the names of all profiles in the base directory.
+func Profiles() ([]string, error) {
+	names := []string{DefaultProfile}
+	entries, err := os.ReadDir(filepath.Join(baseDir, "profiles"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return names, nil
+		}
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != DefaultProfile && profileNamePattern.MatchString(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names[1:])
+	return names, nil
+}
@@ -0,0 +1,133 @@
+package config
+
+import "strings"
+
+// DefaultPort is used for connections that set no port themselves and inherit none.
+const DefaultPort = 22
+
+// Where a resolved setting came from.
+const (
+	SourceConnection = "connection"
+	SourceGroup      = "group"
+	SourceDefaults   = "defaults"
+	SourceBuiltin    = "built-in"
+)
+
+// Resolved is a connection with its inherited settings filled in.
+type Resolved struct {
+	Connection
+	// Sources maps the JSON name of each setting that has a value, such as "port", to
+	// where the value came from.
+	Sources map[string]string
+}
+
+type settingsLayer struct {
+	source   string
+	settings Defaults
+}
+
+// Resolve fills in the settings a connection does not set from the defaults of its
+// group, then from the global defaults. The key and the password alias are inherited
+// together, so that a connection choosing one way to authenticate does not pick up the
+// other from its group.
+func (d *Document) Resolve(c Connection) Resolved {
+	layers := []settingsLayer{{SourceConnection, c.Settings()}}
+	if group, ok := d.Groups[c.Group]; ok && c.Group != "" {
+		layers = append(layers, settingsLayer{SourceGroup, group})
+	}
+	layers = append(layers, settingsLayer{SourceDefaults, d.Defaults})
+
+	r := Resolved{Connection: c, Sources: make(map[string]string)}
+	pick := func(name string, value func(Defaults) string, set func(string)) {
+		for _, l := range layers {
+			v := value(l.settings)
+			if v == "" {
+				continue
+			}
+			// A jump host shared by a group must not be used by the jump host itself.
+			if name == "proxy_jump" && l.source != SourceConnection && jumpsThrough(v, c.Name) {
+				continue
+			}
+			set(v)
+			r.Sources[name] = l.source
+			return
+		}
+	}
+	pick("user", func(s Defaults) string { return s.User }, func(v string) { r.User = v })
+	pick("bandwidth_limit", func(s Defaults) string { return s.BandwidthLimit }, func(v string) { r.BandwidthLimit = v })
+	pick("control_persist", func(s Defaults) string { return s.ControlPersist }, func(v string) { r.ControlPersist = v })
+	pick("proxy_jump", func(s Defaults) string { return s.ProxyJump }, func(v string) { r.ProxyJump = v })
+
+	r.Port = DefaultPort
+	r.Sources["port"] = SourceBuiltin
+	for _, l := range layers {
+		if l.settings.Port != 0 {
+			r.Port = l.settings.Port
+			r.Sources["port"] = l.source
+			break
+		}
+	}
+
+	for _, l := range layers {
+		if l.settings.KeyPath != "" || l.settings.CredentialAlias != "" {
+			r.KeyPath, r.CredentialAlias = l.settings.KeyPath, l.settings.CredentialAlias
+			if r.KeyPath != "" {
+				r.Sources["key_path"] = l.source
+			}
+			if r.CredentialAlias != "" {
+				r.Sources["credential_alias"] = l.source
+			}
+			break
+		}
+	}
+	return r
+}
+
+// Rebase returns c, a connection of the document from, with the settings it inherits
+// there written into the connection wherever d would resolve them differently, so that
+// the connection keeps its settings when it is moved to d.
+func (d *Document) Rebase(c Connection, from *Document) Connection {
+	want, got := from.Resolve(c), d.Resolve(c)
+	if want.User != got.User {
+		c.User = want.User
+	}
+	if want.Port != got.Port {
+		c.Port = want.Port
+	}
+	if want.KeyPath != got.KeyPath || want.CredentialAlias != got.CredentialAlias {
+		c.KeyPath, c.CredentialAlias = want.KeyPath, want.CredentialAlias
+	}
+	if want.BandwidthLimit != got.BandwidthLimit {
+		c.BandwidthLimit = want.BandwidthLimit
+	}
+	if want.ControlPersist != got.ControlPersist {
+		c.ControlPersist = want.ControlPersist
+	}
+	if want.ProxyJump != got.ProxyJump {
+		c.ProxyJump = want.ProxyJump
+	}
+	return c
+}
+
+// Settings returns the inheritable settings the connection sets itself.
+func (c Connection) Settings() Defaults {
+	return Defaults{
+		User:            c.User,
+		Port:            c.Port,
+		KeyPath:         c.KeyPath,
+		CredentialAlias: c.CredentialAlias,
+		BandwidthLimit:  c.BandwidthLimit,
+		ControlPersist:  c.ControlPersist,
+		ProxyJump:       c.ProxyJump,
+	}
+}
+
+// jumpsThrough reports whether the comma-separated jump host list names the connection.
+func jumpsThrough(proxyJump, name string) bool {
+	for _, hop := range strings.Split(proxyJump, ",") {
+		if strings.TrimSpace(hop) == name {
+			return true
+		}
+	}
+	return false
+}
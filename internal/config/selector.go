@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var tagKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_./-]*$`)
+
+// ValidateTag checks a connection tag. A tag is either a plain name such as "canary"
+// or a key=value label such as "env=prod".
+func ValidateTag(tag string) error {
+	key, value, isLabel := strings.Cut(tag, "=")
+	if !tagKeyPattern.MatchString(key) || (isLabel && strings.ContainsAny(value, ",=")) {
+		return fmt.Errorf("invalid tag '%s'; use a name such as canary or a label such as env=prod", tag)
+	}
+	if key == "name" || key == "group" {
+		return fmt.Errorf("invalid tag '%s'; '%s' is reserved", tag, key)
+	}
+	return nil
+}
+
+// Label returns the value of a key=value tag of the connection, or "" for a plain tag.
+// The keys "name" and "group" refer to the connection's name and group. The second
+// result reports whether the connection has the key at all.
+func (c Connection) Label(key string) (string, bool) {
+	switch key {
+	case "name":
+		return c.Name, true
+	case "group":
+		return c.Group, c.Group != ""
+	}
+	for _, tag := range c.Tags {
+		k, v, _ := strings.Cut(tag, "=")
+		if k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Selector picks connections by their tags. It is a comma-separated list of terms that
+// must all match:
+//
+//	key=value   the connection has the label with a value matching the glob pattern
+//	key!=value  the connection does not have such a label
+//	key         the connection has the tag or label
+//	!key        the connection does not have the tag or label
+type Selector struct {
+	text  string
+	terms []selectorTerm
+}
+
+type selectorTerm struct {
+	key      string
+	value    string
+	hasValue bool
+	negate   bool
+}
+
+// ParseSelector parses a selector expression such as "env=prod,role=web,!canary".
+func ParseSelector(s string) (Selector, error) {
+	sel := Selector{text: s}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var t selectorTerm
+		switch {
+		case strings.Contains(part, "!="):
+			t.key, t.value, _ = strings.Cut(part, "!=")
+			t.hasValue, t.negate = true, true
+		case strings.Contains(part, "="):
+			t.key, t.value, _ = strings.Cut(part, "=")
+			t.value = strings.TrimPrefix(t.value, "=")
+			t.hasValue = true
+		case strings.HasPrefix(part, "!"):
+			t.key, t.negate = part[1:], true
+		default:
+			t.key = part
+		}
+		t.key, t.value = strings.TrimSpace(t.key), strings.TrimSpace(t.value)
+		if !tagKeyPattern.MatchString(t.key) {
+			return Selector{}, fmt.Errorf("invalid selector term '%s'", part)
+		}
+		if _, err := path.Match(t.value, ""); err != nil {
+			return Selector{}, fmt.Errorf("invalid selector term '%s': %w", part, err)
+		}
+		sel.terms = append(sel.terms, t)
+	}
+	if len(sel.terms) == 0 {
+		return Selector{}, fmt.Errorf("empty selector")
+	}
+	return sel, nil
+}
+
+// String returns the selector as it was written.
+func (s Selector) String() string {
+	return s.text
+}
+
+// Matches reports whether the connection matches every term of the selector.
+func (s Selector) Matches(c Connection) bool {
+	for _, t := range s.terms {
+		value, ok := c.Label(t.key)
+		match := ok
+		if ok && t.hasValue {
+			match, _ = path.Match(t.value, value)
+		}
+		if match == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// Select returns the connections matching the selector.
+func Select(connections []Connection, s Selector) []*Connection {
+	var matched []*Connection
+	for i, c := range connections {
+		if s.Matches(c) {
+			matched = append(matched, &connections[i])
+		}
+	}
+	return matched
+}
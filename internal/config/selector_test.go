@@ -0,0 +1,131 @@
+package config
+
+import "testing"
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		wantErr  bool
+	}{
+		{"env=prod", false},
+		{"env=prod,role=web,!canary", false},
+		{"env!=prod", false},
+		{" env = prod , canary ", false},
+		{"env=web[", true},
+		{"", true},
+		{" , ", true},
+		{"=prod", true},
+		{"!", true},
+		{"-env=prod", true},
+	}
+	for _, tt := range tests {
+		_, err := ParseSelector(tt.selector)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSelector(%q) error = %v, wantErr %v", tt.selector, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSelectorMatches(t *testing.T) {
+	conn := Connection{
+		Name:  "web1",
+		Group: "prod",
+		Tags:  []string{"env=prod", "role=web", "canary", "region=eu-west-1"},
+	}
+	ungrouped := Connection{Name: "db1", Tags: []string{"env=staging"}}
+
+	tests := []struct {
+		selector string
+		conn     Connection
+		want     bool
+	}{
+		{"env=prod", conn, true},
+		{"env=staging", conn, false},
+		{"env=prod,role=web", conn, true},
+		{"env=prod,role=db", conn, false},
+		{"canary", conn, true},
+		{"env", conn, true},
+		{"owner", conn, false},
+		{"region=eu-*", conn, true},
+		{"region=us-*", conn, false},
+		{"role=w?b", conn, true},
+
+		// Negation.
+		{"!canary", conn, false},
+		{"!canary", ungrouped, true},
+		{"!owner", conn, true},
+		{"env!=prod", conn, false},
+		{"env!=staging", conn, true},
+		{"env!=prod", ungrouped, true},
+		{"role!=web", ungrouped, true},
+		{"env=prod,!canary", conn, false},
+		{"env=staging,!canary", ungrouped, true},
+
+		// A plain tag has an empty value.
+		{"canary=", conn, true},
+		{"canary=x", conn, false},
+
+		// name and group refer to the connection itself.
+		{"name=web*", conn, true},
+		{"name=db*", conn, false},
+		{"group=prod", conn, true},
+		{"group", ungrouped, false},
+		{"!group", ungrouped, true},
+	}
+	for _, tt := range tests {
+		sel, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Fatalf("ParseSelector(%q): %v", tt.selector, err)
+		}
+		if got := sel.Matches(tt.conn); got != tt.want {
+			t.Errorf("%q matches %s = %v, want %v", tt.selector, tt.conn.Name, got, tt.want)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	connections := []Connection{
+		{Name: "a", Tags: []string{"env=prod"}},
+		{Name: "b", Tags: []string{"env=staging"}},
+		{Name: "c", Tags: []string{"env=prod", "canary"}},
+	}
+	sel, err := ParseSelector("env=prod,!canary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	matched := Select(connections, sel)
+	if len(matched) != 1 || matched[0].Name != "a" {
+		t.Fatalf("Select = %v, want [a]", matched)
+	}
+	// The result points into the slice, so that callers can change the connections.
+	if matched[0] != &connections[0] {
+		t.Error("Select did not return a pointer into the slice")
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+	tests := []struct {
+		tag     string
+		wantErr bool
+	}{
+		{"canary", false},
+		{"env=prod", false},
+		{"env=", false},
+		{"team.io/owner=ops", false},
+		{"inventory=/etc/ansible/hosts", false},
+		{"", true},
+		{"-canary", true},
+		{"has space", true},
+		{"env=a,b", true},
+		{"env=a=b", true},
+		{"=prod", true},
+		{"name=web1", true},
+		{"group", true},
+	}
+	for _, tt := range tests {
+		err := ValidateTag(tt.tag)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateTag(%q) error = %v, wantErr %v", tt.tag, err, tt.wantErr)
+		}
+	}
+}
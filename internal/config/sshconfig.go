@@ -0,0 +1,299 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SSHConfigDirective is a directive of an OpenSSH client configuration file that
+// cannot be represented in a gossh connection.
+type SSHConfigDirective struct {
+	Name string
+	File string
+	Line int
+}
+
+// sshConfigOption is a single keyword and value of a Host block.
+type sshConfigOption struct {
+	key   string
+	value string
+}
+
+// sshConfigBlock is a Host or Match block, or the options before the first of them.
+type sshConfigBlock struct {
+	// patterns are the Host patterns; nil for the options at the top of the file,
+	// which apply to every host.
+	patterns []string
+	// match is set for Match blocks, whose conditions are not evaluated.
+	match   bool
+	options []sshConfigOption
+}
+
+// sshConfigSupported are the keywords, in lower case, that map onto a connection.
+var sshConfigSupported = map[string]bool{
+	"hostname":     true,
+	"user":         true,
+	"port":         true,
+	"identityfile": true,
+	"proxyjump":    true,
+}
+
+// sshConfigImplied are keywords, in lower case, whose effect gossh has anyway: it only
+// ever offers the configured key.
+var sshConfigImplied = map[string]bool{
+	"identitiesonly": true,
+}
+
+// sshConfigParser collects the blocks of a configuration file and the files it includes.
+type sshConfigParser struct {
+	// dir is the directory of the top-level file; relative Include paths are relative to it.
+	dir         string
+	blocks      []*sshConfigBlock
+	aliases     []string
+	seenAlias   map[string]bool
+	unsupported []SSHConfigDirective
+	seenKey     map[string]bool
+}
+
+// ParseSSHConfig reads an OpenSSH client configuration file, following Include
+// directives, and returns a connection for every host alias named by a Host line that
+// is not a pattern. Settings are resolved the way ssh does: for each keyword, the
+// first value from a matching block wins. The directives gossh cannot represent are
+// returned as well, once per keyword.
+func ParseSSHConfig(file string) ([]Connection, []SSHConfigDirective, error) {
+	p := &sshConfigParser{
+		dir:       filepath.Dir(file),
+		blocks:    []*sshConfigBlock{{}},
+		seenAlias: make(map[string]bool),
+		seenKey:   make(map[string]bool),
+	}
+	if err := p.parseFile(file, 0); err != nil {
+		return nil, nil, err
+	}
+
+	var connections []Connection
+	for _, alias := range p.aliases {
+		connections = append(connections, p.resolve(alias))
+	}
+	return connections, p.unsupported, nil
+}
+
+// parseFile reads the lines of a configuration file into blocks.
+func (p *sshConfigParser) parseFile(file string, depth int) error {
+	if depth > 16 {
+		return fmt.Errorf("%s: too many nested Include directives", file)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		keyword, value := splitSSHConfigLine(line)
+		key := strings.ToLower(keyword)
+		switch key {
+		case "host":
+			block := &sshConfigBlock{patterns: splitSSHConfigArgs(value)}
+			p.blocks = append(p.blocks, block)
+			for _, pattern := range block.patterns {
+				if !strings.ContainsAny(pattern, "*?!") && !p.seenAlias[pattern] {
+					p.seenAlias[pattern] = true
+					p.aliases = append(p.aliases, pattern)
+				}
+			}
+		case "match":
+			p.blocks = append(p.blocks, &sshConfigBlock{match: true})
+			p.report(keyword, file, lineNo)
+		case "include":
+			// Options after the Include belong to the block it appears in, not to the
+			// last Host block of the included files.
+			enclosing := p.blocks[len(p.blocks)-1]
+			for _, pattern := range splitSSHConfigArgs(value) {
+				pattern = expandHome(pattern)
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(p.dir, pattern)
+				}
+				matches, err := filepath.Glob(pattern)
+				if err != nil {
+					return fmt.Errorf("%s:%d: %v", file, lineNo, err)
+				}
+				for _, match := range matches {
+					if err := p.parseFile(match, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+			p.blocks = append(p.blocks, &sshConfigBlock{patterns: enclosing.patterns, match: enclosing.match})
+		default:
+			block := p.blocks[len(p.blocks)-1]
+			if block.match {
+				continue
+			}
+			if sshConfigImplied[key] {
+				continue
+			}
+			if !sshConfigSupported[key] {
+				p.report(keyword, file, lineNo)
+				continue
+			}
+			if key == "port" {
+				if _, err := strconv.Atoi(unquote(value)); err != nil {
+					return fmt.Errorf("%s:%d: invalid port '%s'", file, lineNo, value)
+				}
+			}
+			block.options = append(block.options, sshConfigOption{key: key, value: unquote(value)})
+		}
+	}
+	return scanner.Err()
+}
+
+// report records an unsupported keyword the first time it is seen.
+func (p *sshConfigParser) report(keyword, file string, line int) {
+	key := strings.ToLower(keyword)
+	if p.seenKey[key] {
+		return
+	}
+	p.seenKey[key] = true
+	p.unsupported = append(p.unsupported, SSHConfigDirective{Name: keyword, File: file, Line: line})
+}
+
+// resolve returns the connection for a host alias.
+func (p *sshConfigParser) resolve(alias string) Connection {
+	settings := make(map[string]string)
+	for _, block := range p.blocks {
+		if block.match || !block.matches(alias) {
+			continue
+		}
+		for _, option := range block.options {
+			if _, ok := settings[option.key]; !ok {
+				settings[option.key] = option.value
+			}
+		}
+	}
+
+	conn := Connection{Name: alias, Host: alias, Port: 22, User: settings["user"]}
+	if conn.User == "" {
+		if u, err := user.Current(); err == nil {
+			conn.User = u.Username
+		}
+	}
+	if hostName, ok := settings["hostname"]; ok {
+		conn.Host = expandSSHTokens(hostName, alias, conn.User)
+	}
+	if port, ok := settings["port"]; ok {
+		conn.Port, _ = strconv.Atoi(port)
+	}
+	if identityFile, ok := settings["identityfile"]; ok && strings.ToLower(identityFile) != "none" {
+		conn.KeyPath = expandHome(expandSSHTokens(identityFile, conn.Host, conn.User))
+	}
+	if proxyJump, ok := settings["proxyjump"]; ok && strings.ToLower(proxyJump) != "none" {
+		conn.ProxyJump = proxyJump
+	}
+	return conn
+}
+
+// matches reports whether the block applies to a host alias. A negated pattern that
+// matches excludes the host even if another pattern matches.
+func (b *sshConfigBlock) matches(alias string) bool {
+	if b.patterns == nil {
+		return true
+	}
+	matched := false
+	for _, pattern := range b.patterns {
+		negated := strings.HasPrefix(pattern, "!")
+		ok, _ := path.Match(strings.TrimPrefix(pattern, "!"), alias)
+		if ok && negated {
+			return false
+		}
+		if ok {
+			matched = true
+		}
+	}
+	return matched
+}
+
+// splitSSHConfigLine splits a line into its keyword and the rest. The keyword may be
+// followed by whitespace or an equals sign.
+func splitSSHConfigLine(line string) (string, string) {
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return line, ""
+	}
+	value := strings.TrimSpace(line[i:])
+	value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+	return line[:i], value
+}
+
+// splitSSHConfigArgs splits a value into whitespace separated arguments, which may
+// be double quoted.
+func splitSSHConfigArgs(value string) []string {
+	var args []string
+	var current strings.Builder
+	quoted := false
+	for _, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case (r == ' ' || r == '\t') && !quoted:
+			if current.Len() > 0 {
+				args = append(args, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// unquote removes the double quotes around a value.
+func unquote(value string) string {
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// expandSSHTokens replaces the %h, %r, %u, %d and %% tokens ssh supports in
+// HostName and IdentityFile.
+func expandSSHTokens(value, host, remoteUser string) string {
+	if !strings.Contains(value, "%") {
+		return value
+	}
+	home, _ := os.UserHomeDir()
+	localUser := ""
+	if u, err := user.Current(); err == nil {
+		localUser = u.Username
+	}
+	return strings.NewReplacer("%%", "%", "%h", host, "%r", remoteUser, "%u", localUser, "%d", home).Replace(value)
+}
+
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, p[1:])
+}
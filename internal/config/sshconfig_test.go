@@ -0,0 +1,69 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSSHConfigInclude(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config": `Include config.d/*
+User alice
+IdentityFile /keys/global
+
+Host web
+    HostName web.example.com
+    Include extra
+    Port 2200
+
+Host db
+    HostName db.example.com
+`,
+		"config.d/app": `Host app
+    HostName app.example.com
+    User bob
+`,
+		"config.d/cache": `Host cache
+    HostName cache.example.com
+`,
+		"extra": `Host other
+    HostName other.example.com
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	connections, _, err := ParseSSHConfig(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Connection{
+		"app":   {Name: "app", Host: "app.example.com", User: "bob", Port: 22, KeyPath: "/keys/global"},
+		"cache": {Name: "cache", Host: "cache.example.com", User: "alice", Port: 22, KeyPath: "/keys/global"},
+		"web":   {Name: "web", Host: "web.example.com", User: "alice", Port: 2200, KeyPath: "/keys/global"},
+		"other": {Name: "other", Host: "other.example.com", User: "alice", Port: 22, KeyPath: "/keys/global"},
+		"db":    {Name: "db", Host: "db.example.com", User: "alice", Port: 22, KeyPath: "/keys/global"},
+	}
+	if len(connections) != len(want) {
+		t.Fatalf("got %d connections, want %d: %+v", len(connections), len(want), connections)
+	}
+	for _, c := range connections {
+		w, ok := want[c.Name]
+		if !ok {
+			t.Errorf("unexpected connection %q", c.Name)
+			continue
+		}
+		if c.Host != w.Host || c.User != w.User || c.Port != w.Port || c.KeyPath != w.KeyPath {
+			t.Errorf("%s = %+v, want %+v", c.Name, c, w)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package ssh
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"gossh/internal/i18n"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cheggaaa/pb/v3"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Archive formats accepted in TransferOptions.Archive.
+const (
+	ArchiveTar  = "tar"
+	ArchiveGzip = "gzip"
+	ArchiveZstd = "zstd"
+)
+
+// errArchiveUnsupported is returned when a directory cannot be sent as an archive,
+// because the server lacks the tools needed or because files at the destination
+// would have to be reported as already existing. The caller then falls back to
+// copying over SFTP.
+var errArchiveUnsupported = errors.New("archive transfer not supported by server")
+
+// remoteCompressor returns the shell commands compressing and decompressing a stream
+// on the server for the given archive format.
+func remoteCompressor(format string) (compress, decompress string) {
+	switch format {
+	case ArchiveGzip:
+		return "gzip -c", "gzip -dc"
+	case ArchiveZstd:
+		return "zstd -q -c", "zstd -q -dc"
+	}
+	return "", ""
+}
+
+// checkArchiveSupport reports errArchiveUnsupported if tar, or the compressor for the
+// archive format of opts, is not available on the server.
+func checkArchiveSupport(sftpClient *sftpConn, opts TransferOptions) error {
+	format := opts.Archive
+	session, err := sftpClient.ssh.NewSession()
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.session", map[string]interface{}{"Error": err}, err)
+	}
+	defer session.Close()
+
+	check := "command -v tar >/dev/null 2>&1"
+	if format == ArchiveGzip || format == ArchiveZstd {
+		check += fmt.Sprintf(" && command -v %s >/dev/null 2>&1", format)
+	}
+	if err := session.Run(check); err != nil {
+		opts.println(i18n.TWith("ssh.archive.fallback", map[string]interface{}{"Format": format}))
+		return errArchiveUnsupported
+	}
+	return nil
+}
+
+// remoteExtractCommand returns the command unpacking an archive read from stdin into dir.
+func remoteExtractCommand(dir, format string, force bool) string {
+	_, decompress := remoteCompressor(format)
+	command := fmt.Sprintf("mkdir -p %s && cd %s && ", shellQuote(dir), shellQuote(dir))
+	if decompress != "" {
+		command += decompress + " | "
+	}
+	if force {
+		return command + "tar -xf -"
+	}
+	// Existing files are kept. GNU tar reports them as errors with -k, so it is told
+	// to skip them instead; other implementations skip them with -k.
+	return command + "{ if tar --version 2>/dev/null | grep -q 'GNU tar'; then tar -xf - --skip-old-files; else tar -xkf -; fi; }"
+}
+
+// remoteCreateCommand returns the command writing an archive of name, relative to dir,
+// to stdout.
+func remoteCreateCommand(dir, name, format string) string {
+	compress, _ := remoteCompressor(format)
+	command := fmt.Sprintf("cd %s && tar -cf - %s", shellQuote(dir), shellQuote(name))
+	if compress != "" {
+		command += " | " + compress
+	}
+	return command
+}
+
+// archiveLayout decides where a directory copy unpacks and what the archive contains,
+// following the same rules as the SFTP copy: copying into an existing directory
+// creates a subdirectory named after the source, otherwise the destination receives
+// the source's contents.
+func archiveLayout(srcPath, dstPath string, dstIsDir bool) (extractDir, srcDir, srcName string) {
+	if dstIsDir {
+		return dstPath, filepath.Dir(srcPath), filepath.Base(srcPath)
+	}
+	return dstPath, srcPath, "."
+}
+
+// uploadDirArchive uploads a directory as a single tar stream unpacked by tar on the server.
+func uploadDirArchive(sftpClient *sftpConn, localPath, remotePath string, opts TransferOptions) error {
+	if err := checkArchiveSupport(sftpClient, opts); err != nil {
+		return err
+	}
+
+	remoteInfo, err := sftpClient.Stat(remotePath)
+	extractDir, srcDir, srcName := archiveLayout(localPath, remotePath, err == nil && remoteInfo.IsDir())
+	if opts.policy() == OverwriteError {
+		if _, err := sftpClient.Stat(filepath.Join(extractDir, srcName)); err == nil {
+			return errArchiveUnsupported
+		}
+	}
+
+	var totalSize int64
+	filepath.WalkDir(localPath, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				totalSize += info.Size()
+			}
+		}
+		return nil
+	})
+
+	session, err := sftpClient.ssh.NewSession()
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.session", map[string]interface{}{"Error": err}, err)
+	}
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.session", map[string]interface{}{"Error": err}, err)
+	}
+	session.Stderr = os.Stderr
+
+	if err := session.Start(remoteExtractCommand(extractDir, opts.Archive, opts.policy() == OverwriteAlways)); err != nil {
+		return i18n.ErrorWith("ssh.error.running.command", map[string]interface{}{"Error": err}, err)
+	}
+
+	bar := opts.startBar(totalSize)
+	writeErr := writeTarStream(stdin, srcDir, srcName, opts, bar)
+	opts.finishBar(bar)
+	stdin.Close()
+
+	if err := session.Wait(); err != nil {
+		return i18n.ErrorWith("ssh.error.running.command", map[string]interface{}{"Error": err}, err)
+	}
+	if writeErr != nil {
+		return i18n.ErrorWith("ssh.error.copying.file", map[string]interface{}{"Error": writeErr}, writeErr)
+	}
+
+	opts.println(i18n.TWith("ssh.uploaded", map[string]interface{}{
+		"Local":  localPath,
+		"Remote": remotePath,
+		"Bytes":  totalSize,
+	}))
+	return nil
+}
+
+// downloadDirArchive downloads a directory as a single tar stream created by tar on the
+// server and unpacks it locally.
+func downloadDirArchive(sftpClient *sftpConn, remotePath, localPath string, opts TransferOptions) error {
+	if err := checkArchiveSupport(sftpClient, opts); err != nil {
+		return err
+	}
+
+	localInfo, err := os.Stat(localPath)
+	extractDir, srcDir, srcName := archiveLayout(remotePath, localPath, err == nil && localInfo.IsDir())
+	if opts.policy() == OverwriteError {
+		if _, err := os.Stat(filepath.Join(extractDir, srcName)); err == nil {
+			return errArchiveUnsupported
+		}
+	}
+
+	var totalSize int64
+	walker := sftpClient.Walk(remotePath)
+	for walker.Step() {
+		if walker.Err() == nil && walker.Stat().Mode().IsRegular() {
+			totalSize += walker.Stat().Size()
+		}
+	}
+
+	session, err := sftpClient.ssh.NewSession()
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.session", map[string]interface{}{"Error": err}, err)
+	}
+	defer session.Close()
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.session", map[string]interface{}{"Error": err}, err)
+	}
+	session.Stderr = os.Stderr
+
+	if err := session.Start(remoteCreateCommand(srcDir, srcName, opts.Archive)); err != nil {
+		return i18n.ErrorWith("ssh.error.running.command", map[string]interface{}{"Error": err}, err)
+	}
+
+	bar := opts.startBar(totalSize)
+	readErr := readTarStream(opts.reader(stdout), extractDir, opts, bar)
+	opts.finishBar(bar)
+
+	if readErr != nil {
+		// Drain the stream so the remote tar is not blocked writing.
+		io.Copy(io.Discard, stdout)
+	}
+	if err := session.Wait(); err != nil {
+		return i18n.ErrorWith("ssh.error.running.command", map[string]interface{}{"Error": err}, err)
+	}
+	if readErr != nil {
+		return readErr
+	}
+
+	opts.println(i18n.TWith("ssh.downloaded", map[string]interface{}{
+		"Remote": remotePath,
+		"Local":  localPath,
+		"Bytes":  totalSize,
+	}))
+	return nil
+}
+
+// copyRemoteDirArchive copies a directory between two servers by piping the output of
+// tar on the source into tar on the destination.
+func copyRemoteDirArchive(srcSftp *sftpConn, srcPath string, dstSftp *sftpConn, dstPath string, opts TransferOptions) error {
+	if err := checkArchiveSupport(srcSftp, opts); err != nil {
+		return err
+	}
+	if err := checkArchiveSupport(dstSftp, opts); err != nil {
+		return err
+	}
+
+	dstInfo, err := dstSftp.Stat(dstPath)
+	extractDir, srcDir, srcName := archiveLayout(srcPath, dstPath, err == nil && dstInfo.IsDir())
+	if opts.policy() == OverwriteError {
+		if _, err := dstSftp.Stat(filepath.Join(extractDir, srcName)); err == nil {
+			return errArchiveUnsupported
+		}
+	}
+
+	srcSession, err := srcSftp.ssh.NewSession()
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.session", map[string]interface{}{"Error": err}, err)
+	}
+	defer srcSession.Close()
+
+	dstSession, err := dstSftp.ssh.NewSession()
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.session", map[string]interface{}{"Error": err}, err)
+	}
+	defer dstSession.Close()
+
+	stdout, err := srcSession.StdoutPipe()
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.session", map[string]interface{}{"Error": err}, err)
+	}
+	srcSession.Stderr = os.Stderr
+	dstSession.Stderr = os.Stderr
+
+	// The size of the compressed stream is unknown, so the bar only counts bytes.
+	bar := opts.startBar(0)
+	dstSession.Stdin = bar.NewProxyReader(opts.reader(stdout))
+
+	if err := dstSession.Start(remoteExtractCommand(extractDir, opts.Archive, opts.policy() == OverwriteAlways)); err != nil {
+		return i18n.ErrorWith("ssh.error.running.command", map[string]interface{}{"Error": err}, err)
+	}
+	if err := srcSession.Start(remoteCreateCommand(srcDir, srcName, opts.Archive)); err != nil {
+		return i18n.ErrorWith("ssh.error.running.command", map[string]interface{}{"Error": err}, err)
+	}
+
+	srcErr := srcSession.Wait()
+	dstErr := dstSession.Wait()
+	opts.finishBar(bar)
+	for _, err := range []error{srcErr, dstErr} {
+		if err != nil {
+			return i18n.ErrorWith("ssh.error.running.command", map[string]interface{}{"Error": err}, err)
+		}
+	}
+
+	opts.println(i18n.TWith("ssh.copied", map[string]interface{}{
+		"Source":      srcPath,
+		"Destination": dstPath,
+		"Bytes":       bar.Current(),
+	}))
+	return nil
+}
+
+// writeTarStream writes name, relative to dir, as a tar archive compressed according
+// to opts.Archive.
+func writeTarStream(w io.Writer, dir, name string, opts TransferOptions, bar *pb.ProgressBar) error {
+	var compressor io.WriteCloser
+	switch opts.Archive {
+	case ArchiveGzip:
+		compressor = gzip.NewWriter(w)
+	case ArchiveZstd:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		compressor = zw
+	}
+	if compressor != nil {
+		w = compressor
+	}
+
+	tw := tar.NewWriter(w)
+	root := filepath.Join(dir, name)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, bar.NewProxyReader(opts.reader(f)))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if compressor != nil {
+		return compressor.Close()
+	}
+	return nil
+}
+
+// readTarStream unpacks a tar archive, compressed according to opts.Archive, into dir.
+// Entries that would be written outside dir are rejected.
+func readTarStream(r io.Reader, dir string, opts TransferOptions, bar *pb.ProgressBar) error {
+	switch opts.Archive {
+	case ArchiveGzip:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return i18n.ErrorWith("ssh.error.archive.read", map[string]interface{}{"Error": err}, err)
+		}
+		defer gr.Close()
+		r = gr
+	case ArchiveZstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return i18n.ErrorWith("ssh.error.archive.read", map[string]interface{}{"Error": err}, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return i18n.ErrorWith("ssh.error.create.local.dir", map[string]interface{}{"Error": err}, err)
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return i18n.ErrorWith("ssh.error.archive.read", map[string]interface{}{"Error": err}, err)
+		}
+
+		target := filepath.Join(root, filepath.FromSlash(header.Name))
+		if !withinDir(root, target) {
+			return i18n.Error("ssh.error.archive.unsafe.path", map[string]interface{}{"Path": header.Name})
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, header.FileInfo().Mode().Perm()|0700); err != nil {
+				return i18n.ErrorWith("ssh.error.create.local.dir", map[string]interface{}{"Error": err}, err)
+			}
+		case tar.TypeReg:
+			if err := extractTarFile(tr, target, header, opts.policy() == OverwriteAlways, bar); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			linkTarget := header.Linkname
+			if !filepath.IsAbs(linkTarget) {
+				linkTarget = filepath.Join(filepath.Dir(target), linkTarget)
+			}
+			if !withinDir(root, linkTarget) {
+				return i18n.Error("ssh.error.archive.unsafe.path", map[string]interface{}{"Path": header.Name})
+			}
+			if _, err := os.Lstat(target); err == nil {
+				if opts.policy() != OverwriteAlways {
+					continue
+				}
+				os.Remove(target)
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return i18n.ErrorWith("ssh.error.create.local.file", map[string]interface{}{"Error": err}, err)
+			}
+		}
+	}
+}
+
+// extractTarFile writes the current tar entry to target. Unless force is set, an
+// existing file is kept.
+func extractTarFile(tr *tar.Reader, target string, header *tar.Header, force bool, bar *pb.ProgressBar) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return i18n.ErrorWith("ssh.error.create.local.dir", map[string]interface{}{"Error": err}, err)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(target, flags, header.FileInfo().Mode().Perm())
+	if err != nil {
+		if os.IsExist(err) {
+			// Existing files are kept; skip over the entry.
+			_, err := io.Copy(io.Discard, bar.NewProxyReader(tr))
+			return err
+		}
+		return i18n.ErrorWith("ssh.error.create.local.file", map[string]interface{}{"Error": err}, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, bar.NewProxyReader(tr)); err != nil {
+		return i18n.ErrorWith("ssh.error.copying.file", map[string]interface{}{"Error": err}, err)
+	}
+	return nil
+}
+
+// withinDir reports whether path is dir or lies inside it.
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, filepath.Clean(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
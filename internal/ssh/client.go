@@ -1,12 +1,15 @@
 package ssh
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"gossh/internal/config"
 	"gossh/internal/i18n"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/cheggaaa/pb/v3"
 	"github.com/pkg/sftp"
@@ -14,8 +17,17 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 )
 
+// authSecrets holds what the user typed while authenticating, so that it can be handed
+// to a mux process instead of being asked for again.
+type authSecrets struct {
+	Password   string `json:"password,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
 // getAuthMethods determines the authentication methods based on the connection configuration.
-func getAuthMethods(conn *config.Connection) ([]ssh.AuthMethod, error) {
+// A password or passphrase already present in secrets is used instead of prompting; one that
+// is prompted for is stored in secrets.
+func getAuthMethods(conn *config.Connection, secrets *authSecrets) ([]ssh.AuthMethod, error) {
 	var authMethods []ssh.AuthMethod
 
 	if conn.KeyPath != "" {
@@ -25,13 +37,16 @@ func getAuthMethods(conn *config.Connection) ([]ssh.AuthMethod, error) {
 		}
 		signer, err := ssh.ParsePrivateKey(key)
 		if err != nil {
-			fmt.Print(i18n.T("ssh.enter.passphrase"))
-			bytePassword, err := terminal.ReadPassword(int(os.Stdin.Fd()))
-			fmt.Println()
-			if err != nil {
-				return nil, i18n.ErrorWith("ssh.error.reading.passphrase", map[string]interface{}{"Error": err}, err)
+			if secrets.Passphrase == "" {
+				fmt.Print(i18n.T("ssh.enter.passphrase"))
+				bytePassword, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Println()
+				if err != nil {
+					return nil, i18n.ErrorWith("ssh.error.reading.passphrase", map[string]interface{}{"Error": err}, err)
+				}
+				secrets.Passphrase = string(bytePassword)
 			}
-			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, bytePassword)
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(secrets.Passphrase))
 			if err != nil {
 				return nil, i18n.ErrorWith("ssh.error.parsing.key", map[string]interface{}{"Error": err}, err)
 			}
@@ -54,29 +69,54 @@ func getAuthMethods(conn *config.Connection) ([]ssh.AuthMethod, error) {
 		}
 		authMethods = append(authMethods, ssh.Password(foundPassword))
 	} else {
-		fmt.Print(i18n.T("ssh.enter.password"))
-		bytePassword, err := terminal.ReadPassword(int(os.Stdin.Fd()))
-		if err != nil {
+		if secrets.Password == "" {
+			fmt.Print(i18n.T("ssh.enter.password"))
+			bytePassword, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+			if err != nil {
+				fmt.Println()
+				return nil, i18n.ErrorWith("ssh.error.reading.password", map[string]interface{}{"Error": err}, err)
+			}
 			fmt.Println()
-			return nil, i18n.ErrorWith("ssh.error.reading.password", map[string]interface{}{"Error": err}, err)
+			secrets.Password = string(bytePassword)
 		}
-		fmt.Println()
-		authMethods = append(authMethods, ssh.Password(string(bytePassword)))
+		authMethods = append(authMethods, ssh.Password(secrets.Password))
 	}
 	return authMethods, nil
 }
 
-// newClient creates a new SSH client.
+// newClient creates a new SSH client. If the connection has a ControlPersist setting,
+// the client runs over the connection held by a mux process, which is started first
+// if needed.
 func newClient(conn *config.Connection) (*ssh.Client, error) {
-	authMethods, err := getAuthMethods(conn)
+	enabled, _, err := ParseControlPersist(conn.ControlPersist)
 	if err != nil {
 		return nil, err
 	}
+	secrets := &authSecrets{}
+	if enabled {
+		client, err := muxClient(conn, secrets)
+		if err == nil {
+			return client, nil
+		}
+		fmt.Println(i18n.TWith("ssh.mux.unavailable", map[string]interface{}{"Error": err}))
+	}
+	return dialClient(conn, secrets)
+}
 
-	sshConfig := &ssh.ClientConfig{
-		User:            conn.User,
-		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+// dialClient connects and authenticates to the server directly, or through its jump
+// hosts.
+func dialClient(conn *config.Connection, secrets *authSecrets) (*ssh.Client, error) {
+	return dialClientDepth(conn, secrets, 0)
+}
+
+// dialClientDepth is dialClient for a connection reached as a jump host at the given depth.
+func dialClientDepth(conn *config.Connection, secrets *authSecrets, depth int) (*ssh.Client, error) {
+	sshConfig, err := clientConfig(conn, secrets)
+	if err != nil {
+		return nil, err
+	}
+	if conn.ProxyJump != "" {
+		return dialThroughJumpHosts(conn, sshConfig, depth)
 	}
 
 	fmt.Println(i18n.TWith("ssh.connecting", map[string]interface{}{
@@ -91,6 +131,19 @@ func newClient(conn *config.Connection) (*ssh.Client, error) {
 	return client, nil
 }
 
+// clientConfig returns the SSH client configuration for a connection.
+func clientConfig(conn *config.Connection, secrets *authSecrets) (*ssh.ClientConfig, error) {
+	authMethods, err := getAuthMethods(conn, secrets)
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.ClientConfig{
+		User:            conn.User,
+		Auth:            authMethods,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	}, nil
+}
+
 // Connect establishes an interactive SSH session.
 func Connect(conn *config.Connection) {
 	client, err := newClient(conn)
@@ -178,47 +231,175 @@ func TestConnection(conn *config.Connection) error {
 	return nil
 }
 
+// TransferOptions controls how files are copied by the upload, download and
+// remote-to-remote copy functions.
+type TransferOptions struct {
+	// Recursive allows directories to be copied.
+	Recursive bool
+	// Force overwrites existing destination files. It is the same as setting
+	// Overwrite to OverwriteAlways.
+	Force bool
+	// Overwrite is the policy for destination files that already exist, one of
+	// OverwritePolicies. It defaults to OverwriteError.
+	Overwrite string
+	// DryRun reports which files would be created, overwritten or skipped without
+	// transferring anything.
+	DryRun bool
+	// Verify compares the SHA-256 checksum of each copied file with the
+	// checksum of the file on the remote server.
+	Verify bool
+	// Archive, when set, copies directories as a single tar stream over an exec
+	// session instead of file by file over SFTP. It names the compression used:
+	// ArchiveTar for none, ArchiveGzip or ArchiveZstd. If the server lacks tar or
+	// the compressor, the SFTP copy is used instead.
+	Archive string
+	// Limiter throttles the transfer when set. It may be shared by several
+	// concurrent transfers to cap their combined bandwidth.
+	Limiter *RateLimiter
+
+	// row is the progress bar of the host when the transfer is part of a fan-out.
+	row *pb.ProgressBar
+	// prompt keeps the answers given in ask mode.
+	prompt *overwritePrompt
+}
+
+// startBar returns the progress bar for a file of the given size. Transfers that
+// are part of a fan-out draw on their host's row instead of starting a new bar.
+func (o TransferOptions) startBar(size int64) *pb.ProgressBar {
+	if o.row != nil {
+		o.row.SetTotal(size)
+		o.row.SetCurrent(0)
+		return o.row
+	}
+	bar := pb.Full.Start64(size)
+	bar.Set(pb.Bytes, true)
+	return bar
+}
+
+// finishBar completes a bar returned by startBar.
+func (o TransferOptions) finishBar(bar *pb.ProgressBar) {
+	if o.row == nil {
+		bar.Finish()
+	}
+}
+
+// println prints a progress message, unless the transfer is drawing on a fan-out row.
+func (o TransferOptions) println(message string) {
+	if o.row == nil {
+		fmt.Println(message)
+	}
+}
+
+// reader wraps r with the bandwidth limiter, if any.
+func (o TransferOptions) reader(r io.Reader) io.Reader {
+	if o.Limiter == nil {
+		return r
+	}
+	return o.Limiter.Reader(r)
+}
+
+// sftpConn is an SFTP client together with the SSH connection it runs on. The SSH
+// connection is needed for work SFTP cannot do itself, such as running commands.
+type sftpConn struct {
+	*sftp.Client
+	ssh *ssh.Client
+}
+
+// newSFTPConn connects to the server and starts an SFTP session on it.
+func newSFTPConn(conn *config.Connection) (*sftpConn, error) {
+	client, err := newClient(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	sftpClient, err := sftp.NewClient(client)
+	if err != nil {
+		client.Close()
+		return nil, i18n.ErrorWith("ssh.error.sftp.client", map[string]interface{}{"Error": err}, err)
+	}
+	return &sftpConn{Client: sftpClient, ssh: client}, nil
+}
+
+// Close closes the SFTP session and the underlying SSH connection.
+func (c *sftpConn) Close() error {
+	c.Client.Close()
+	return c.ssh.Close()
+}
+
 // UploadFile uploads a local file or directory to the remote server.
 func UploadFile(conn *config.Connection, localPath, remotePath string, recursive bool) error {
-	return UploadFileWithOpts(conn, localPath, remotePath, recursive, false)
+	return UploadFileWithOpts(conn, localPath, remotePath, TransferOptions{Recursive: recursive})
 }
 
 // UploadFileWithOpts uploads a local file or directory to the remote server with options.
-func UploadFileWithOpts(conn *config.Connection, localPath, remotePath string, recursive, force bool) error {
-	client, err := newClient(conn)
+func UploadFileWithOpts(conn *config.Connection, localPath, remotePath string, opts TransferOptions) error {
+	return UploadFilesWithOpts(conn, []string{localPath}, remotePath, opts)
+}
+
+// UploadFilesWithOpts uploads one or more local files or directories to the remote server.
+// Sources may contain glob patterns. When more than one source is given, remotePath must be
+// an existing directory.
+func UploadFilesWithOpts(conn *config.Connection, localPaths []string, remotePath string, opts TransferOptions) error {
+	sources, err := expandLocalPaths(localPaths)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
 
-	sftpClient, err := sftp.NewClient(client)
+	sftpClient, err := newSFTPConn(conn)
 	if err != nil {
-		return i18n.ErrorWith("ssh.error.sftp.client", map[string]interface{}{"Error": err}, err)
+		return err
 	}
 	defer sftpClient.Close()
 
+	return uploadFiles(sftpClient, sources, remotePath, opts.prepare())
+}
+
+// uploadFiles uploads already expanded local sources over an open SFTP connection.
+func uploadFiles(sftpClient *sftpConn, sources []string, remotePath string, opts TransferOptions) error {
+	if len(sources) > 1 {
+		remoteInfo, err := sftpClient.Stat(remotePath)
+		if err != nil || !remoteInfo.IsDir() {
+			return i18n.Error("ssh.error.target.not.dir", map[string]interface{}{"Path": remotePath})
+		}
+	}
+
+	for _, localPath := range sources {
+		if err := uploadPath(sftpClient, localPath, remotePath, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadPath uploads a single local file or directory.
+func uploadPath(sftpClient *sftpConn, localPath, remotePath string, opts TransferOptions) error {
 	localInfo, err := os.Stat(localPath)
 	if err != nil {
 		return i18n.ErrorWith("ssh.error.stat.local", map[string]interface{}{"Error": err}, err)
 	}
 
 	if localInfo.IsDir() {
-		if !recursive {
+		if !opts.Recursive {
 			return i18n.ErrorWith("ssh.error.directory.recursive", map[string]interface{}{"Path": localPath}, fmt.Errorf("is directory"))
 		}
-		return uploadDir(sftpClient, localPath, remotePath)
+		if opts.Archive != "" && opts.archivable() {
+			err := uploadDirArchive(sftpClient, localPath, remotePath, opts)
+			if err != errArchiveUnsupported {
+				return err
+			}
+		}
+		remoteInfo, err := sftpClient.Stat(remotePath)
+		if err == nil && remoteInfo.IsDir() {
+			remotePath = filepath.Join(remotePath, filepath.Base(localPath))
+		}
+		return uploadDir(sftpClient, localPath, remotePath, opts)
 	}
 
-	return uploadFileWithOpts(sftpClient, localPath, remotePath, force)
-}
-
-// uploadFile uploads a single file.
-func uploadFile(sftpClient *sftp.Client, localPath, remotePath string) error {
-	return uploadFileWithOpts(sftpClient, localPath, remotePath, false)
+	return uploadFileWithOpts(sftpClient, localPath, remotePath, opts)
 }
 
 // uploadFileWithOpts uploads a single file with options.
-func uploadFileWithOpts(sftpClient *sftp.Client, localPath, remotePath string, force bool) error {
+func uploadFileWithOpts(sftpClient *sftpConn, localPath, remotePath string, opts TransferOptions) error {
 	srcFile, err := os.Open(localPath)
 	if err != nil {
 		return i18n.ErrorWith("ssh.error.open.local.file", map[string]interface{}{"Error": err}, err)
@@ -237,11 +418,16 @@ func uploadFileWithOpts(sftpClient *sftp.Client, localPath, remotePath string, f
 		remotePath = filepath.Join(remotePath, localFileName)
 	}
 
-	remoteInfo, err = sftpClient.Stat(remotePath)
-	if err == nil {
-		if !force {
-			return i18n.ErrorWith("ssh.error.remote.exists", map[string]interface{}{"Path": remotePath}, fmt.Errorf("file exists"))
-		}
+	if remoteInfo, err = sftpClient.Stat(remotePath); err != nil {
+		remoteInfo = nil
+	}
+	write, err := opts.checkOverwrite(remotePath, fileInfo, remoteInfo, func() (bool, error) {
+		return sameChecksum(
+			func() (string, error) { return localChecksum(localPath) },
+			func() (string, error) { return remoteChecksum(sftpClient, remotePath) })
+	})
+	if err != nil || !write {
+		return err
 	}
 
 	dstFile, err := sftpClient.Create(remotePath)
@@ -250,18 +436,27 @@ func uploadFileWithOpts(sftpClient *sftp.Client, localPath, remotePath string, f
 	}
 	defer dstFile.Close()
 
-	bar := pb.Full.Start64(fileSize)
-	bar.Set(pb.Bytes, true)
-	barReader := bar.NewProxyReader(srcFile)
+	bar := opts.startBar(fileSize)
+	barReader := bar.NewProxyReader(opts.reader(srcFile))
 
-	bytes, err := io.Copy(dstFile, barReader)
-	bar.Finish()
+	hash := sha256.New()
+	bytes, err := io.Copy(dstFile, io.TeeReader(barReader, hash))
+	opts.finishBar(bar)
 
 	if err != nil {
 		return i18n.ErrorWith("ssh.error.copying.file", map[string]interface{}{"Error": err}, err)
 	}
 
-	fmt.Println(i18n.TWith("ssh.uploaded", map[string]interface{}{
+	if opts.Verify {
+		if err := dstFile.Close(); err != nil {
+			return i18n.ErrorWith("ssh.error.copying.file", map[string]interface{}{"Error": err}, err)
+		}
+		if err := verifyChecksum(sftpClient, remotePath, hash, opts); err != nil {
+			return err
+		}
+	}
+
+	opts.println(i18n.TWith("ssh.uploaded", map[string]interface{}{
 		"Local":  localPath,
 		"Remote": remotePath,
 		"Bytes":  bytes,
@@ -270,10 +465,11 @@ func uploadFileWithOpts(sftpClient *sftp.Client, localPath, remotePath string, f
 }
 
 // uploadDir recursively uploads a directory.
-func uploadDir(sftpClient *sftp.Client, localPath, remotePath string) error {
-	err := sftpClient.MkdirAll(remotePath)
-	if err != nil {
-		return i18n.ErrorWith("ssh.error.create.remote.dir", map[string]interface{}{"Error": err}, err)
+func uploadDir(sftpClient *sftpConn, localPath, remotePath string, opts TransferOptions) error {
+	if !opts.DryRun {
+		if err := sftpClient.MkdirAll(remotePath); err != nil {
+			return i18n.ErrorWith("ssh.error.create.remote.dir", map[string]interface{}{"Error": err}, err)
+		}
 	}
 
 	entries, err := os.ReadDir(localPath)
@@ -286,12 +482,12 @@ func uploadDir(sftpClient *sftp.Client, localPath, remotePath string) error {
 		remoteFilePath := filepath.Join(remotePath, entry.Name())
 
 		if entry.IsDir() {
-			err = uploadDir(sftpClient, localFilePath, remoteFilePath)
+			err = uploadDir(sftpClient, localFilePath, remoteFilePath, opts)
 			if err != nil {
 				return err
 			}
 		} else {
-			err = uploadFile(sftpClient, localFilePath, remoteFilePath)
+			err = uploadFileWithOpts(sftpClient, localFilePath, remoteFilePath, opts)
 			if err != nil {
 				return err
 			}
@@ -303,45 +499,74 @@ func uploadDir(sftpClient *sftp.Client, localPath, remotePath string) error {
 
 // DownloadFile downloads a remote file or directory to the local machine.
 func DownloadFile(conn *config.Connection, remotePath, localPath string, recursive bool) error {
-	return DownloadFileWithOpts(conn, remotePath, localPath, recursive, false)
+	return DownloadFileWithOpts(conn, remotePath, localPath, TransferOptions{Recursive: recursive})
 }
 
 // DownloadFileWithOpts downloads a remote file or directory to the local machine with options.
-func DownloadFileWithOpts(conn *config.Connection, remotePath, localPath string, recursive, force bool) error {
-	client, err := newClient(conn)
+func DownloadFileWithOpts(conn *config.Connection, remotePath, localPath string, opts TransferOptions) error {
+	return DownloadFilesWithOpts(conn, []string{remotePath}, localPath, opts)
+}
+
+// DownloadFilesWithOpts downloads one or more remote files or directories to the local machine.
+// Sources may contain glob patterns, which are expanded on the remote server. When more than
+// one source is given, localPath must be an existing directory.
+func DownloadFilesWithOpts(conn *config.Connection, remotePaths []string, localPath string, opts TransferOptions) error {
+	sftpClient, err := newSFTPConn(conn)
 	if err != nil {
 		return err
 	}
-	defer client.Close()
+	defer sftpClient.Close()
 
-	sftpClient, err := sftp.NewClient(client)
+	sources, err := expandRemotePaths(sftpClient, remotePaths)
 	if err != nil {
-		return i18n.ErrorWith("ssh.error.sftp.client", map[string]interface{}{"Error": err}, err)
+		return err
 	}
-	defer sftpClient.Close()
+	opts = opts.prepare()
 
+	if len(sources) > 1 {
+		localInfo, err := os.Stat(localPath)
+		if err != nil || !localInfo.IsDir() {
+			return i18n.Error("ssh.error.target.not.dir", map[string]interface{}{"Path": localPath})
+		}
+	}
+
+	for _, remotePath := range sources {
+		if err := downloadPath(sftpClient, remotePath, localPath, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// downloadPath downloads a single remote file or directory.
+func downloadPath(sftpClient *sftpConn, remotePath, localPath string, opts TransferOptions) error {
 	remoteInfo, err := sftpClient.Stat(remotePath)
 	if err != nil {
 		return i18n.ErrorWith("ssh.error.stat.remote", map[string]interface{}{"Error": err}, err)
 	}
 
 	if remoteInfo.IsDir() {
-		if !recursive {
+		if !opts.Recursive {
 			return i18n.ErrorWith("ssh.error.directory.recursive", map[string]interface{}{"Path": remotePath}, fmt.Errorf("is directory"))
 		}
-		return downloadDir(sftpClient, remotePath, localPath)
+		if opts.Archive != "" && opts.archivable() {
+			err := downloadDirArchive(sftpClient, remotePath, localPath, opts)
+			if err != errArchiveUnsupported {
+				return err
+			}
+		}
+		localInfo, err := os.Stat(localPath)
+		if err == nil && localInfo.IsDir() {
+			localPath = filepath.Join(localPath, filepath.Base(remotePath))
+		}
+		return downloadDir(sftpClient, remotePath, localPath, opts)
 	}
 
-	return downloadFileWithOpts(sftpClient, remotePath, localPath, force)
-}
-
-// downloadFile downloads a single file.
-func downloadFile(sftpClient *sftp.Client, remotePath, localPath string) error {
-	return downloadFileWithOpts(sftpClient, remotePath, localPath, false)
+	return downloadFileWithOpts(sftpClient, remotePath, localPath, opts)
 }
 
 // downloadFileWithOpts downloads a single file with options.
-func downloadFileWithOpts(sftpClient *sftp.Client, remotePath, localPath string, force bool) error {
+func downloadFileWithOpts(sftpClient *sftpConn, remotePath, localPath string, opts TransferOptions) error {
 	srcFile, err := sftpClient.Open(remotePath)
 	if err != nil {
 		return i18n.ErrorWith("ssh.error.open.remote.file", map[string]interface{}{"Error": err}, err)
@@ -360,11 +585,16 @@ func downloadFileWithOpts(sftpClient *sftp.Client, remotePath, localPath string,
 		localPath = filepath.Join(localPath, remoteFileName)
 	}
 
-	localInfo, err = os.Stat(localPath)
-	if err == nil {
-		if !force {
-			return i18n.ErrorWith("ssh.error.local.exists", map[string]interface{}{"Path": localPath}, fmt.Errorf("file exists"))
-		}
+	if localInfo, err = os.Stat(localPath); err != nil {
+		localInfo = nil
+	}
+	write, err := opts.checkOverwrite(localPath, fileInfo, localInfo, func() (bool, error) {
+		return sameChecksum(
+			func() (string, error) { return remoteChecksum(sftpClient, remotePath) },
+			func() (string, error) { return localChecksum(localPath) })
+	})
+	if err != nil || !write {
+		return err
 	}
 
 	dstFile, err := os.Create(localPath)
@@ -373,18 +603,24 @@ func downloadFileWithOpts(sftpClient *sftp.Client, remotePath, localPath string,
 	}
 	defer dstFile.Close()
 
-	bar := pb.Full.Start64(fileSize)
-	bar.Set(pb.Bytes, true)
-	barReader := bar.NewProxyReader(srcFile)
+	bar := opts.startBar(fileSize)
+	barReader := bar.NewProxyReader(opts.reader(srcFile))
 
-	bytes, err := io.Copy(dstFile, barReader)
-	bar.Finish()
+	hash := sha256.New()
+	bytes, err := io.Copy(io.MultiWriter(dstFile, hash), barReader)
+	opts.finishBar(bar)
 
 	if err != nil {
 		return i18n.ErrorWith("ssh.error.copying.file", map[string]interface{}{"Error": err}, err)
 	}
 
-	fmt.Println(i18n.TWith("ssh.downloaded", map[string]interface{}{
+	if opts.Verify {
+		if err := verifyChecksum(sftpClient, remotePath, hash, opts); err != nil {
+			return err
+		}
+	}
+
+	opts.println(i18n.TWith("ssh.downloaded", map[string]interface{}{
 		"Remote": remotePath,
 		"Local":  localPath,
 		"Bytes":  bytes,
@@ -393,10 +629,11 @@ func downloadFileWithOpts(sftpClient *sftp.Client, remotePath, localPath string,
 }
 
 // downloadDir recursively downloads a directory.
-func downloadDir(sftpClient *sftp.Client, remotePath, localPath string) error {
-	err := os.MkdirAll(localPath, 0755)
-	if err != nil {
-		return i18n.ErrorWith("ssh.error.create.local.dir", map[string]interface{}{"Error": err}, err)
+func downloadDir(sftpClient *sftpConn, remotePath, localPath string, opts TransferOptions) error {
+	if !opts.DryRun {
+		if err := os.MkdirAll(localPath, 0755); err != nil {
+			return i18n.ErrorWith("ssh.error.create.local.dir", map[string]interface{}{"Error": err}, err)
+		}
 	}
 
 	entries, err := sftpClient.ReadDir(remotePath)
@@ -409,12 +646,12 @@ func downloadDir(sftpClient *sftp.Client, remotePath, localPath string) error {
 		localFilePath := filepath.Join(localPath, entry.Name())
 
 		if entry.IsDir() {
-			err = downloadDir(sftpClient, remoteFilePath, localFilePath)
+			err = downloadDir(sftpClient, remoteFilePath, localFilePath, opts)
 			if err != nil {
 				return err
 			}
 		} else {
-			err = downloadFile(sftpClient, remoteFilePath, localFilePath)
+			err = downloadFileWithOpts(sftpClient, remoteFilePath, localFilePath, opts)
 			if err != nil {
 				return err
 			}
@@ -423,3 +660,275 @@ func downloadDir(sftpClient *sftp.Client, remotePath, localPath string) error {
 
 	return nil
 }
+
+// CopyRemoteToRemote copies one or more files or directories from one remote server to
+// another, streaming the data through the local machine. Sources may contain glob patterns,
+// which are expanded on the source server.
+func CopyRemoteToRemote(srcConn *config.Connection, srcPaths []string, dstConn *config.Connection, dstPath string, opts TransferOptions) error {
+	srcSftp, err := newSFTPConn(srcConn)
+	if err != nil {
+		return err
+	}
+	defer srcSftp.Close()
+
+	sources, err := expandRemotePaths(srcSftp, srcPaths)
+	if err != nil {
+		return err
+	}
+	opts = opts.prepare()
+
+	dstSftp, err := newSFTPConn(dstConn)
+	if err != nil {
+		return err
+	}
+	defer dstSftp.Close()
+
+	if len(sources) > 1 {
+		dstInfo, err := dstSftp.Stat(dstPath)
+		if err != nil || !dstInfo.IsDir() {
+			return i18n.Error("ssh.error.target.not.dir", map[string]interface{}{"Path": dstPath})
+		}
+	}
+
+	for _, srcPath := range sources {
+		if err := copyRemotePath(srcSftp, srcPath, dstSftp, dstPath, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyRemotePath copies a single file or directory between two SFTP clients.
+func copyRemotePath(srcSftp *sftpConn, srcPath string, dstSftp *sftpConn, dstPath string, opts TransferOptions) error {
+	srcInfo, err := srcSftp.Stat(srcPath)
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.stat.remote", map[string]interface{}{"Error": err}, err)
+	}
+
+	if srcInfo.IsDir() {
+		if !opts.Recursive {
+			return i18n.ErrorWith("ssh.error.directory.recursive", map[string]interface{}{"Path": srcPath}, fmt.Errorf("is directory"))
+		}
+		if opts.Archive != "" && opts.archivable() {
+			err := copyRemoteDirArchive(srcSftp, srcPath, dstSftp, dstPath, opts)
+			if err != errArchiveUnsupported {
+				return err
+			}
+		}
+		dstInfo, err := dstSftp.Stat(dstPath)
+		if err == nil && dstInfo.IsDir() {
+			dstPath = filepath.Join(dstPath, filepath.Base(srcPath))
+		}
+		return copyRemoteDir(srcSftp, srcPath, dstSftp, dstPath, opts)
+	}
+
+	return copyRemoteFile(srcSftp, srcPath, dstSftp, dstPath, opts)
+}
+
+// copyRemoteFile copies a single file between two SFTP clients.
+func copyRemoteFile(srcSftp *sftpConn, srcPath string, dstSftp *sftpConn, dstPath string, opts TransferOptions) error {
+	srcFile, err := srcSftp.Open(srcPath)
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.open.remote.file", map[string]interface{}{"Error": err}, err)
+	}
+	defer srcFile.Close()
+
+	fileInfo, err := srcFile.Stat()
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.get.file.info", map[string]interface{}{"Error": err}, err)
+	}
+	fileSize := fileInfo.Size()
+
+	dstInfo, err := dstSftp.Stat(dstPath)
+	if err == nil && dstInfo.IsDir() {
+		dstPath = filepath.Join(dstPath, filepath.Base(srcPath))
+	}
+
+	if dstInfo, err = dstSftp.Stat(dstPath); err != nil {
+		dstInfo = nil
+	}
+	write, err := opts.checkOverwrite(dstPath, fileInfo, dstInfo, func() (bool, error) {
+		return sameChecksum(
+			func() (string, error) { return remoteChecksum(srcSftp, srcPath) },
+			func() (string, error) { return remoteChecksum(dstSftp, dstPath) })
+	})
+	if err != nil || !write {
+		return err
+	}
+
+	dstFile, err := dstSftp.Create(dstPath)
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.create.remote.file", map[string]interface{}{"Error": err}, err)
+	}
+	defer dstFile.Close()
+
+	bar := opts.startBar(fileSize)
+	barReader := bar.NewProxyReader(opts.reader(srcFile))
+
+	hash := sha256.New()
+	bytes, err := io.Copy(dstFile, io.TeeReader(barReader, hash))
+	opts.finishBar(bar)
+
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.copying.file", map[string]interface{}{"Error": err}, err)
+	}
+
+	if opts.Verify {
+		if err := dstFile.Close(); err != nil {
+			return i18n.ErrorWith("ssh.error.copying.file", map[string]interface{}{"Error": err}, err)
+		}
+		if err := verifyChecksum(dstSftp, dstPath, hash, opts); err != nil {
+			return err
+		}
+	}
+
+	opts.println(i18n.TWith("ssh.copied", map[string]interface{}{
+		"Source":      srcPath,
+		"Destination": dstPath,
+		"Bytes":       bytes,
+	}))
+	return nil
+}
+
+// copyRemoteDir recursively copies a directory between two SFTP clients.
+func copyRemoteDir(srcSftp *sftpConn, srcPath string, dstSftp *sftpConn, dstPath string, opts TransferOptions) error {
+	if !opts.DryRun {
+		if err := dstSftp.MkdirAll(dstPath); err != nil {
+			return i18n.ErrorWith("ssh.error.create.remote.dir", map[string]interface{}{"Error": err}, err)
+		}
+	}
+
+	entries, err := srcSftp.ReadDir(srcPath)
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.read.remote.dir", map[string]interface{}{"Error": err}, err)
+	}
+
+	for _, entry := range entries {
+		srcFilePath := filepath.Join(srcPath, entry.Name())
+		dstFilePath := filepath.Join(dstPath, entry.Name())
+
+		if entry.IsDir() {
+			err = copyRemoteDir(srcSftp, srcFilePath, dstSftp, dstFilePath, opts)
+		} else {
+			err = copyRemoteFile(srcSftp, srcFilePath, dstSftp, dstFilePath, opts)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DirectCopyRemoteToRemote copies one or more files or directories between two remote servers by
+// running scp on the source server, so the data never passes through the local machine.
+// The source server must be able to reach and authenticate to the destination itself.
+func DirectCopyRemoteToRemote(srcConn *config.Connection, srcPaths []string, dstConn *config.Connection, dstPath string, recursive bool) error {
+	sftpClient, err := newSFTPConn(srcConn)
+	if err != nil {
+		return err
+	}
+	defer sftpClient.Close()
+
+	sources, err := expandRemotePaths(sftpClient, srcPaths)
+	if err != nil {
+		return err
+	}
+
+	session, err := sftpClient.ssh.NewSession()
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.session", map[string]interface{}{"Error": err}, err)
+	}
+	defer session.Close()
+
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+	session.Stdin = os.Stdin
+
+	// The remote scp may prompt for a password, so give it a terminal when we have one.
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		oldState, err := terminal.MakeRaw(fd)
+		if err != nil {
+			return i18n.ErrorWith("ssh.error.raw.terminal", map[string]interface{}{"Error": err}, err)
+		}
+		defer terminal.Restore(fd, oldState)
+
+		termWidth, termHeight, err := terminal.GetSize(fd)
+		if err != nil {
+			termWidth = 80
+			termHeight = 24
+		}
+		if err := session.RequestPty("xterm-256color", termHeight, termWidth, ssh.TerminalModes{}); err != nil {
+			return i18n.ErrorWith("ssh.error.pty", map[string]interface{}{"Error": err}, err)
+		}
+	}
+
+	command := "scp"
+	if recursive {
+		command += " -r"
+	}
+	command += fmt.Sprintf(" -P %d", dstConn.Port)
+	for _, srcPath := range sources {
+		command += " " + shellQuote(srcPath)
+	}
+	command += " " + shellQuote(fmt.Sprintf("%s@%s:%s", dstConn.User, dstConn.Host, dstPath))
+
+	if err := session.Run(command); err != nil {
+		return i18n.ErrorWith("ssh.error.running.command", map[string]interface{}{"Error": err}, err)
+	}
+	return nil
+}
+
+// shellQuote quotes s for safe use as a single argument in a POSIX shell command.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// hasGlobMeta reports whether path contains any of the glob metacharacters
+// recognised by filepath.Match.
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// expandLocalPaths expands glob patterns in local source paths. Paths without
+// metacharacters are returned unchanged so that a missing file is reported by stat.
+func expandLocalPaths(patterns []string) ([]string, error) {
+	var paths []string
+	for _, pattern := range patterns {
+		if !hasGlobMeta(pattern) {
+			paths = append(paths, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, i18n.ErrorWith("ssh.error.glob", map[string]interface{}{"Pattern": pattern, "Error": err}, err)
+		}
+		if len(matches) == 0 {
+			return nil, i18n.Error("ssh.error.glob.no.match", map[string]interface{}{"Pattern": pattern})
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+// expandRemotePaths expands glob patterns in remote source paths on the server.
+func expandRemotePaths(sftpClient *sftpConn, patterns []string) ([]string, error) {
+	var paths []string
+	for _, pattern := range patterns {
+		if !hasGlobMeta(pattern) {
+			paths = append(paths, pattern)
+			continue
+		}
+		matches, err := sftpClient.Glob(pattern)
+		if err != nil {
+			return nil, i18n.ErrorWith("ssh.error.glob", map[string]interface{}{"Pattern": pattern, "Error": err}, err)
+		}
+		if len(matches) == 0 {
+			return nil, i18n.Error("ssh.error.glob.no.match", map[string]interface{}{"Pattern": pattern})
+		}
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
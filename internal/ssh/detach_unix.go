@@ -0,0 +1,11 @@
+//go:build !windows
+
+package ssh
+
+import "syscall"
+
+// detachedProcAttr starts a process in its own session, so that it keeps running when
+// the terminal it was started from is closed.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package ssh
+
+import "syscall"
+
+// detachedProcess is the DETACHED_PROCESS process creation flag.
+const detachedProcess = 0x00000008
+
+// detachedProcAttr starts a process without a console, so that it keeps running when
+// the console it was started from is closed.
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
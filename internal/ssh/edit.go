@@ -0,0 +1,193 @@
+package ssh
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/sftp"
+)
+
+// EditRemoteFile downloads a remote file to a private temporary file, opens it in the
+// user's editor and uploads it back if it was changed. The original mode and owner of the
+// remote file are preserved. If the remote file changed while it was being edited, the
+// user is asked before it is overwritten.
+func EditRemoteFile(conn *config.Connection, remotePath string) error {
+	sftpClient, err := newSFTPConn(conn)
+	if err != nil {
+		return err
+	}
+	defer sftpClient.Close()
+
+	origInfo, err := sftpClient.Stat(remotePath)
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.stat.remote", map[string]interface{}{"Error": err}, err)
+	}
+	if origInfo.IsDir() {
+		return i18n.Error("edit.error.is.directory", map[string]interface{}{"Path": remotePath})
+	}
+
+	// MkdirTemp creates the directory with mode 0700, so the copy is private to the user.
+	tmpDir, err := os.MkdirTemp("", "gossh-edit-")
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.create.local.file", map[string]interface{}{"Error": err}, err)
+	}
+	localPath := filepath.Join(tmpDir, path.Base(remotePath))
+	keepLocal := false
+	defer func() {
+		if !keepLocal {
+			os.RemoveAll(tmpDir)
+		}
+	}()
+
+	origSum, err := fetchRemoteFile(sftpClient, remotePath, localPath)
+	if err != nil {
+		return err
+	}
+
+	if err := runEditor(localPath); err != nil {
+		return i18n.ErrorWith("edit.error.editor", map[string]interface{}{"Error": err}, err)
+	}
+
+	edited, err := os.ReadFile(localPath)
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.open.local.file", map[string]interface{}{"Error": err}, err)
+	}
+	editedSum := sha256.Sum256(edited)
+	if hex.EncodeToString(editedSum[:]) == origSum {
+		fmt.Println(i18n.TWith("edit.unchanged", map[string]interface{}{"Path": remotePath}))
+		return nil
+	}
+
+	changed, err := remoteFileChanged(sftpClient, remotePath, origInfo, origSum)
+	if err != nil {
+		return err
+	}
+	if changed {
+		fmt.Println(i18n.TWith("edit.conflict", map[string]interface{}{"Path": remotePath}))
+		fmt.Print(i18n.T("edit.confirm.overwrite"))
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			keepLocal = true
+			fmt.Println(i18n.TWith("edit.cancelled", map[string]interface{}{"Local": localPath}))
+			return nil
+		}
+	}
+
+	if err := writeRemoteFile(sftpClient, remotePath, edited, origInfo); err != nil {
+		keepLocal = true
+		fmt.Println(i18n.TWith("edit.kept.local", map[string]interface{}{"Local": localPath}))
+		return err
+	}
+
+	fmt.Println(i18n.TWith("edit.saved", map[string]interface{}{"Path": remotePath, "Bytes": len(edited)}))
+	return nil
+}
+
+// fetchRemoteFile copies a remote file to localPath, readable only by the user, and
+// returns the hex-encoded SHA-256 of its contents.
+func fetchRemoteFile(sftpClient *sftpConn, remotePath, localPath string) (string, error) {
+	srcFile, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return "", i18n.ErrorWith("ssh.error.open.remote.file", map[string]interface{}{"Error": err}, err)
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(localPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", i18n.ErrorWith("ssh.error.create.local.file", map[string]interface{}{"Error": err}, err)
+	}
+	defer dstFile.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(dstFile, hash), srcFile); err != nil {
+		return "", i18n.ErrorWith("ssh.error.copying.file", map[string]interface{}{"Error": err}, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// remoteFileChanged reports whether the remote file differs from the version that was
+// downloaded. The modification time and size are checked first; the contents are only
+// hashed again when those differ.
+func remoteFileChanged(sftpClient *sftpConn, remotePath string, origInfo os.FileInfo, origSum string) (bool, error) {
+	info, err := sftpClient.Stat(remotePath)
+	if err != nil {
+		// The file was removed in the meantime.
+		return true, nil
+	}
+	if info.ModTime().Equal(origInfo.ModTime()) && info.Size() == origInfo.Size() {
+		return false, nil
+	}
+
+	srcFile, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return false, i18n.ErrorWith("ssh.error.open.remote.file", map[string]interface{}{"Error": err}, err)
+	}
+	defer srcFile.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, srcFile); err != nil {
+		return false, i18n.ErrorWith("ssh.error.copying.file", map[string]interface{}{"Error": err}, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)) != origSum, nil
+}
+
+// writeRemoteFile replaces the contents of a remote file in place and restores its
+// original mode and owner.
+func writeRemoteFile(sftpClient *sftpConn, remotePath string, data []byte, origInfo os.FileInfo) error {
+	dstFile, err := sftpClient.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.create.remote.file", map[string]interface{}{"Error": err}, err)
+	}
+	if _, err := dstFile.Write(data); err != nil {
+		dstFile.Close()
+		return i18n.ErrorWith("ssh.error.copying.file", map[string]interface{}{"Error": err}, err)
+	}
+	if err := dstFile.Close(); err != nil {
+		return i18n.ErrorWith("ssh.error.copying.file", map[string]interface{}{"Error": err}, err)
+	}
+
+	if err := sftpClient.Chmod(remotePath, origInfo.Mode().Perm()); err != nil {
+		fmt.Println(i18n.TWith("edit.warning.chmod", map[string]interface{}{"Path": remotePath, "Error": err}))
+	}
+	if stat, ok := origInfo.Sys().(*sftp.FileStat); ok {
+		if err := sftpClient.Chown(remotePath, int(stat.UID), int(stat.GID)); err != nil {
+			fmt.Println(i18n.TWith("edit.warning.chown", map[string]interface{}{"Path": remotePath, "Error": err}))
+		}
+	}
+	return nil
+}
+
+// runEditor opens file in the editor named by $VISUAL or $EDITOR and waits for it to exit.
+func runEditor(file string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	// The editor may include arguments, e.g. "code --wait".
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], file)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,106 @@
+package ssh
+
+import (
+	"fmt"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"strings"
+	"sync"
+
+	"github.com/cheggaaa/pb/v3"
+)
+
+// FanOutResult is the outcome of a fan-out transfer for a single host.
+type FanOutResult struct {
+	Conn *config.Connection
+	Err  error
+}
+
+// FanOutUpload uploads local files to the same remote path on several hosts at once,
+// drawing one progress row per host. Connections are established one at a time so that
+// password prompts do not overlap; a host that fails does not stop the others. The
+// returned results are in the order of conns.
+func FanOutUpload(conns []*config.Connection, localPaths []string, remotePath string, opts TransferOptions) ([]FanOutResult, error) {
+	sources, err := expandLocalPaths(localPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]FanOutResult, len(conns))
+	clients := make([]*sftpConn, len(conns))
+	defer func() {
+		for _, client := range clients {
+			if client != nil {
+				client.Close()
+			}
+		}
+	}()
+
+	for i, conn := range conns {
+		results[i].Conn = conn
+		clients[i], results[i].Err = newSFTPConn(conn)
+	}
+
+	opts = opts.prepare()
+
+	// A dry run prints the plan of each host in turn instead of drawing progress rows.
+	if opts.DryRun {
+		for i, conn := range conns {
+			if results[i].Err != nil {
+				continue
+			}
+			fmt.Println(i18n.TWith("ssh.dryrun.host", map[string]interface{}{"Name": conn.Name}))
+			results[i].Err = uploadFiles(clients[i], sources, remotePath, opts)
+		}
+		return results, nil
+	}
+
+	width := 0
+	for _, conn := range conns {
+		if len(conn.Name) > width {
+			width = len(conn.Name)
+		}
+	}
+
+	rows := make([]*pb.ProgressBar, len(conns))
+	for i, conn := range conns {
+		rows[i] = pb.Full.New(0)
+		rows[i].Set(pb.Bytes, true)
+		rows[i].Set("prefix", conn.Name+strings.Repeat(" ", width-len(conn.Name)))
+		if results[i].Err != nil {
+			rows[i].Set("suffix", i18n.T("scp.fanout.row.failed"))
+		}
+	}
+	// Without a terminal the rows cannot be drawn; the transfers still run and the
+	// caller reports the results.
+	pool, poolErr := pb.StartPool(rows...)
+
+	var wg sync.WaitGroup
+	for i := range conns {
+		if results[i].Err != nil {
+			continue
+		}
+		hostOpts := opts
+		hostOpts.row = rows[i]
+
+		wg.Add(1)
+		go func(i int, hostOpts TransferOptions) {
+			defer wg.Done()
+			results[i].Err = uploadFiles(clients[i], sources, remotePath, hostOpts)
+			if results[i].Err != nil {
+				rows[i].Set("suffix", i18n.T("scp.fanout.row.failed"))
+			} else {
+				rows[i].Set("suffix", i18n.T("scp.fanout.row.done"))
+			}
+		}(i, hostOpts)
+	}
+	wg.Wait()
+
+	for _, row := range rows {
+		row.Finish()
+	}
+	if poolErr == nil {
+		pool.Stop()
+	}
+	return results, nil
+}
@@ -0,0 +1,131 @@
+package ssh
+
+import (
+	"fmt"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// maxJumpDepth limits how deeply jump hosts may themselves use jump hosts, which also
+// stops loops between connections.
+const maxJumpDepth = 8
+
+// dialThroughJumpHosts connects to the server of conn through its jump hosts. The first
+// jump host is dialed like any connection, including its own jump hosts; each further
+// one, and finally the server, is reached through the previous one.
+func dialThroughJumpHosts(conn *config.Connection, sshConfig *ssh.ClientConfig, depth int) (*ssh.Client, error) {
+	if depth >= maxJumpDepth {
+		return nil, i18n.Error("ssh.error.jump.depth", map[string]interface{}{"Name": conn.Name})
+	}
+	hops, err := jumpHosts(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	jump, err := dialClientDepth(hops[0], &authSecrets{}, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	for _, hop := range hops[1:] {
+		hopConfig, err := clientConfig(hop, &authSecrets{})
+		if err != nil {
+			jump.Close()
+			return nil, err
+		}
+		if jump, err = dialThrough(jump, hop, hopConfig); err != nil {
+			return nil, err
+		}
+	}
+	return dialThrough(jump, conn, sshConfig)
+}
+
+// dialThrough connects to the server of conn over a tunnel through jump. The jump
+// client is closed when the new client is closed, or if connecting fails.
+func dialThrough(jump *ssh.Client, conn *config.Connection, sshConfig *ssh.ClientConfig) (*ssh.Client, error) {
+	fmt.Println(i18n.TWith("ssh.connecting", map[string]interface{}{
+		"User": conn.User,
+		"Host": conn.Host,
+		"Port": conn.Port,
+	}))
+	addr := fmt.Sprintf("%s:%d", conn.Host, conn.Port)
+	tunnel, err := jump.Dial("tcp", addr)
+	if err != nil {
+		jump.Close()
+		return nil, i18n.ErrorWith("ssh.error.dialing", map[string]interface{}{"Error": err}, err)
+	}
+	clientConn, chans, reqs, err := ssh.NewClientConn(tunnel, addr, sshConfig)
+	if err != nil {
+		tunnel.Close()
+		jump.Close()
+		return nil, i18n.ErrorWith("ssh.error.dialing", map[string]interface{}{"Error": err}, err)
+	}
+
+	client := ssh.NewClient(clientConn, chans, reqs)
+	go func() {
+		client.Wait()
+		jump.Close()
+	}()
+	return client, nil
+}
+
+// jumpHosts resolves the comma-separated ProxyJump setting of a connection. Each jump
+// host is the name of a saved connection, or [user@]host[:port]; the latter uses the
+// user and credentials of conn unless a user is given.
+func jumpHosts(conn *config.Connection) ([]*config.Connection, error) {
+	connections, err := config.LoadConnections()
+	if err != nil {
+		return nil, i18n.ErrorWith("error.loading.connections", map[string]interface{}{"Error": err}, err)
+	}
+
+	var hops []*config.Connection
+	for _, spec := range strings.Split(conn.ProxyJump, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		if hop := findJumpConnection(connections, spec); hop != nil {
+			hops = append(hops, hop)
+			continue
+		}
+
+		hop := &config.Connection{
+			Name:            spec,
+			User:            conn.User,
+			Host:            spec,
+			Port:            22,
+			KeyPath:         conn.KeyPath,
+			CredentialAlias: conn.CredentialAlias,
+		}
+		if at := strings.LastIndex(hop.Host, "@"); at >= 0 {
+			hop.User, hop.Host = hop.Host[:at], hop.Host[at+1:]
+		}
+		if colon := strings.LastIndex(hop.Host, ":"); colon >= 0 && !strings.HasSuffix(hop.Host, "]") {
+			port, err := strconv.Atoi(hop.Host[colon+1:])
+			if err != nil {
+				return nil, i18n.Error("ssh.error.jump.invalid", map[string]interface{}{"Jump": spec})
+			}
+			hop.Host, hop.Port = hop.Host[:colon], port
+		}
+		hop.Host = strings.TrimSuffix(strings.TrimPrefix(hop.Host, "["), "]")
+		hops = append(hops, hop)
+	}
+
+	if len(hops) == 0 {
+		return nil, i18n.Error("ssh.error.jump.invalid", map[string]interface{}{"Jump": conn.ProxyJump})
+	}
+	return hops, nil
+}
+
+// findJumpConnection returns the saved connection with the given name, or nil.
+func findJumpConnection(connections []config.Connection, name string) *config.Connection {
+	for i, c := range connections {
+		if c.Name == name {
+			return &connections[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,495 @@
+package ssh
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// A mux process holds the SSH connection to a server on a Unix socket so that later
+// invocations do not have to connect and authenticate again, like OpenSSH's
+// ControlMaster. It speaks SSH on the socket: every channel and request opened by a
+// local client is forwarded over the server connection, so sessions, SFTP and port
+// forwarding work unchanged.
+
+// Global requests understood by a mux process.
+const (
+	muxStatusRequest = "status@gossh"
+	muxStopRequest   = "stop@gossh"
+)
+
+// MuxStatus describes a running mux process.
+type MuxStatus struct {
+	Name    string    `json:"name"`
+	User    string    `json:"user"`
+	Host    string    `json:"host"`
+	Port    int       `json:"port"`
+	PID     int       `json:"pid"`
+	Started time.Time `json:"started"`
+	Clients int       `json:"clients"`
+	Persist string    `json:"persist"`
+}
+
+// muxRequest is what the starting process hands to a new mux process on its stdin.
+type muxRequest struct {
+	Conn    config.Connection `json:"conn"`
+	Secrets authSecrets       `json:"secrets"`
+}
+
+// ParseControlPersist parses the control_persist setting of a connection. An empty value
+// or "no" disables the mux. "yes" keeps the mux process running until it is stopped;
+// otherwise the value is a duration such as "10m", or a number of seconds, after which
+// an idle mux process exits.
+func ParseControlPersist(s string) (enabled bool, timeout time.Duration, err error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "no":
+		return false, 0, nil
+	case "yes":
+		return true, 0, nil
+	}
+	if seconds, err := strconv.Atoi(s); err == nil && seconds > 0 {
+		return true, time.Duration(seconds) * time.Second, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return true, d, nil
+	}
+	return false, 0, i18n.Error("ssh.error.control.persist", map[string]interface{}{"Value": s})
+}
+
+// muxDir returns the directory holding the mux sockets.
+func muxDir() string {
+	return filepath.Join(config.Dir(), "mux")
+}
+
+// muxSocketPath returns the socket of the mux process for a connection. The name is
+// derived from the connection's address, so that a changed connection gets a new mux.
+func muxSocketPath(conn *config.Connection) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s %s@%s:%d", conn.Name, conn.User, conn.Host, conn.Port)))
+	return filepath.Join(muxDir(), hex.EncodeToString(sum[:8])+".sock")
+}
+
+// dialMux opens an SSH client connection to a mux process.
+func dialMux(socket string) (*ssh.Client, error) {
+	c, err := net.DialTimeout("unix", socket, 2*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(c, "mux", &ssh.ClientConfig{
+		User:            "gossh",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         2 * time.Second,
+	})
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// muxClient returns a client running over the mux process of the connection, starting
+// the mux process if there is none.
+func muxClient(conn *config.Connection, secrets *authSecrets) (*ssh.Client, error) {
+	socket := muxSocketPath(conn)
+	if client, err := dialMux(socket); err == nil {
+		fmt.Println(i18n.TWith("ssh.mux.reusing", map[string]interface{}{
+			"User": conn.User,
+			"Host": conn.Host,
+			"Port": conn.Port,
+		}))
+		return client, nil
+	}
+
+	if err := startMux(conn, secrets); err != nil {
+		return nil, err
+	}
+	return dialMux(socket)
+}
+
+// startMux starts a mux process for the connection in the background and waits until it
+// is connected. Passwords and passphrases are asked for here and passed on to it.
+func startMux(conn *config.Connection, secrets *authSecrets) error {
+	if _, err := getAuthMethods(conn, secrets); err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, "mux", "serve")
+	// The mux process must use the same configuration directory, which may have been
+	// chosen on the command line.
+	cmd.Env = append(os.Environ(), "GOSSH_HOME="+config.BaseDir(), "GOSSH_PROFILE="+config.Profile())
+	cmd.SysProcAttr = detachedProcAttr()
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(i18n.TWith("ssh.connecting", map[string]interface{}{
+		"User": conn.User,
+		"Host": conn.Host,
+		"Port": conn.Port,
+	}))
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	// The mux process outlives this one; it is never waited for.
+	defer cmd.Process.Release()
+
+	json.NewEncoder(stdin).Encode(muxRequest{Conn: *conn, Secrets: *secrets})
+	stdin.Close()
+
+	reply, _ := bufio.NewReader(stdout).ReadString('\n')
+	reply = strings.TrimSpace(reply)
+	if reply != "ok" {
+		if reply == "" {
+			reply = "mux process exited"
+		}
+		return errors.New(reply)
+	}
+	return nil
+}
+
+// ServeMux runs a mux process. It reads the connection and the secrets typed by the user
+// from r, connects to the server and writes "ok" or the error to ready. It then serves
+// local clients until it has been idle for the ControlPersist timeout, the server
+// connection is lost or it is stopped.
+func ServeMux(r io.Reader, ready *os.File) error {
+	// Nothing may be written to the starting process after the reply.
+	devNull, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	os.Stdout, os.Stderr = devNull, devNull
+
+	m, err := newMuxServer(r)
+	if err != nil {
+		fmt.Fprintln(ready, err)
+		ready.Close()
+		return err
+	}
+	fmt.Fprintln(ready, "ok")
+	ready.Close()
+	if m == nil {
+		// Another mux process already serves this connection.
+		return nil
+	}
+	return m.serve()
+}
+
+// muxServer is the state of a running mux process.
+type muxServer struct {
+	conn     config.Connection
+	upstream *ssh.Client
+	listener net.Listener
+	config   *ssh.ServerConfig
+	timeout  time.Duration
+	started  time.Time
+
+	mu      sync.Mutex
+	clients int
+	idle    *time.Timer
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// newMuxServer listens on the socket of the connection read from r and connects to the
+// server. It returns nil if a mux process for the connection is already running.
+func newMuxServer(r io.Reader) (*muxServer, error) {
+	var req muxRequest
+	if err := json.NewDecoder(r).Decode(&req); err != nil {
+		return nil, err
+	}
+	_, timeout, err := ParseControlPersist(req.Conn.ControlPersist)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(muxDir(), 0700); err != nil {
+		return nil, err
+	}
+	socket := muxSocketPath(&req.Conn)
+	if client, err := dialMux(socket); err == nil {
+		client.Close()
+		return nil, nil
+	}
+	// A socket nobody answers on was left behind by a mux process that is gone.
+	os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	os.Chmod(socket, 0600)
+
+	upstream, err := dialClient(&req.Conn, &req.Secrets)
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		listener.Close()
+		upstream.Close()
+		return nil, err
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		listener.Close()
+		upstream.Close()
+		return nil, err
+	}
+	// Access is restricted by the permissions of the socket and its directory.
+	serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+	serverConfig.AddHostKey(signer)
+
+	return &muxServer{
+		conn:     req.Conn,
+		upstream: upstream,
+		listener: listener,
+		config:   serverConfig,
+		timeout:  timeout,
+		started:  time.Now(),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// serve accepts local clients until the mux process is stopped.
+func (m *muxServer) serve() error {
+	go func() {
+		m.upstream.Wait()
+		m.stop()
+	}()
+	m.track(0)
+
+	for {
+		c, err := m.listener.Accept()
+		if err != nil {
+			select {
+			case <-m.done:
+				return nil
+			default:
+				m.stop()
+				return err
+			}
+		}
+		m.track(1)
+		go func() {
+			defer m.track(-1)
+			m.handle(c)
+		}()
+	}
+}
+
+// stop closes the socket and the server connection.
+func (m *muxServer) stop() {
+	m.stopOnce.Do(func() {
+		close(m.done)
+		m.listener.Close()
+		m.upstream.Close()
+	})
+}
+
+// track updates the number of connected clients and starts the idle timer when the
+// last one is gone.
+func (m *muxServer) track(delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.clients += delta
+	if m.idle != nil {
+		m.idle.Stop()
+		m.idle = nil
+	}
+	if m.clients == 0 && m.timeout > 0 {
+		m.idle = time.AfterFunc(m.timeout, m.stop)
+	}
+}
+
+// status returns the status of the mux process, not counting the client asking for it.
+func (m *muxServer) status() MuxStatus {
+	m.mu.Lock()
+	clients := m.clients - 1
+	m.mu.Unlock()
+
+	persist := m.conn.ControlPersist
+	if m.timeout > 0 {
+		persist = m.timeout.String()
+	}
+	return MuxStatus{
+		Name:    m.conn.Name,
+		User:    m.conn.User,
+		Host:    m.conn.Host,
+		Port:    m.conn.Port,
+		PID:     os.Getpid(),
+		Started: m.started,
+		Clients: clients,
+		Persist: persist,
+	}
+}
+
+// handle serves a single local client.
+func (m *muxServer) handle(c net.Conn) {
+	sshConn, chans, reqs, err := ssh.NewServerConn(c, m.config)
+	if err != nil {
+		c.Close()
+		return
+	}
+	defer sshConn.Close()
+
+	go func() {
+		for req := range reqs {
+			switch req.Type {
+			case muxStatusRequest:
+				payload, _ := json.Marshal(m.status())
+				req.Reply(true, payload)
+			case muxStopRequest:
+				req.Reply(true, nil)
+				m.stop()
+			default:
+				ok, payload, err := m.upstream.SendRequest(req.Type, req.WantReply, req.Payload)
+				req.Reply(ok && err == nil, payload)
+			}
+		}
+	}()
+
+	for newChannel := range chans {
+		go m.forwardChannel(newChannel)
+	}
+}
+
+// forwardChannel opens the same channel on the server connection and copies data and
+// requests between the two until either side closes it.
+func (m *muxServer) forwardChannel(newChannel ssh.NewChannel) {
+	remote, remoteReqs, err := m.upstream.OpenChannel(newChannel.ChannelType(), newChannel.ExtraData())
+	if err != nil {
+		var openErr *ssh.OpenChannelError
+		if errors.As(err, &openErr) {
+			newChannel.Reject(openErr.Reason, openErr.Message)
+		} else {
+			newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		}
+		return
+	}
+	local, localReqs, err := newChannel.Accept()
+	if err != nil {
+		remote.Close()
+		return
+	}
+
+	go func() {
+		io.Copy(remote, local)
+		remote.CloseWrite()
+	}()
+	go io.Copy(remote.Stderr(), local.Stderr())
+	go func() {
+		for req := range localReqs {
+			ok, err := remote.SendRequest(req.Type, req.WantReply, req.Payload)
+			req.Reply(ok && err == nil, nil)
+		}
+		remote.Close()
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(local, remote)
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(local.Stderr(), remote.Stderr())
+	}()
+	copied := make(chan struct{})
+	go func() {
+		wg.Wait()
+		local.CloseWrite()
+		close(copied)
+	}()
+
+	for req := range remoteReqs {
+		ok, err := local.SendRequest(req.Type, req.WantReply, req.Payload)
+		req.Reply(ok && err == nil, nil)
+	}
+	<-copied
+	local.Close()
+}
+
+// MuxStatuses returns the status of every running mux process. Sockets left behind by
+// mux processes that are gone are removed.
+func MuxStatuses() ([]MuxStatus, error) {
+	sockets, err := filepath.Glob(filepath.Join(muxDir(), "*.sock"))
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MuxStatus
+	for _, socket := range sockets {
+		client, err := dialMux(socket)
+		if err != nil {
+			os.Remove(socket)
+			continue
+		}
+		ok, payload, err := client.SendRequest(muxStatusRequest, true, nil)
+		client.Close()
+		if err != nil || !ok {
+			continue
+		}
+		var status MuxStatus
+		if err := json.Unmarshal(payload, &status); err == nil {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// StopMux stops the mux processes of the named connection, or all of them if name is
+// empty. It returns the names of the connections whose mux process was stopped.
+func StopMux(name string) ([]string, error) {
+	sockets, err := filepath.Glob(filepath.Join(muxDir(), "*.sock"))
+	if err != nil {
+		return nil, err
+	}
+
+	var stopped []string
+	for _, socket := range sockets {
+		client, err := dialMux(socket)
+		if err != nil {
+			os.Remove(socket)
+			continue
+		}
+		ok, payload, err := client.SendRequest(muxStatusRequest, true, nil)
+		var status MuxStatus
+		if err == nil && ok && json.Unmarshal(payload, &status) == nil && (name == "" || status.Name == name) {
+			if _, _, err := client.SendRequest(muxStopRequest, true, nil); err == nil {
+				stopped = append(stopped, status.Name)
+			}
+		}
+		client.Close()
+	}
+	return stopped, nil
+}
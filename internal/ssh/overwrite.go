@@ -0,0 +1,201 @@
+package ssh
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"gossh/internal/i18n"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Overwrite policies accepted in TransferOptions.Overwrite.
+const (
+	// OverwriteError stops the transfer with an error when a destination file already
+	// exists. It is the default.
+	OverwriteError = "error"
+	// OverwriteNever skips files that already exist at the destination.
+	OverwriteNever = "never"
+	// OverwriteAsk prompts for each file that already exists.
+	OverwriteAsk = "ask"
+	// OverwriteAlways replaces existing files.
+	OverwriteAlways = "always"
+	// OverwriteIfNewer replaces existing files that are older than the source.
+	OverwriteIfNewer = "if-newer"
+	// OverwriteIfDifferent replaces existing files whose contents differ from the source.
+	OverwriteIfDifferent = "if-different"
+)
+
+// OverwritePolicies lists the valid overwrite policies.
+var OverwritePolicies = []string{OverwriteError, OverwriteNever, OverwriteAsk, OverwriteAlways, OverwriteIfNewer, OverwriteIfDifferent}
+
+// overwritePrompt holds the state of ask mode for a whole transfer, so that an
+// "all" or "skip all" answer applies to the remaining files.
+type overwritePrompt struct {
+	mu     sync.Mutex
+	reader *bufio.Reader
+	// answer is OverwriteAlways or OverwriteNever once the user answered for all files.
+	answer string
+}
+
+// prepare returns the options with the state shared by all files of one transfer.
+func (o TransferOptions) prepare() TransferOptions {
+	if o.prompt == nil {
+		o.prompt = &overwritePrompt{}
+	}
+	return o
+}
+
+// policy returns the effective overwrite policy. Force is the same as OverwriteAlways.
+func (o TransferOptions) policy() string {
+	if o.Force {
+		return OverwriteAlways
+	}
+	if o.Overwrite == "" {
+		return OverwriteError
+	}
+	return o.Overwrite
+}
+
+// archivable reports whether directories may be sent as a tar stream, which can only
+// keep or replace all existing files and cannot report what it would do. With
+// OverwriteError the stream is only used when the destination does not exist yet.
+func (o TransferOptions) archivable() bool {
+	policy := o.policy()
+	return !o.DryRun && (policy == OverwriteError || policy == OverwriteNever || policy == OverwriteAlways)
+}
+
+// checkOverwrite decides whether dst may be written. dstInfo is nil when dst does not
+// exist; same compares the contents of the source and dst and is only called for the
+// if-different policy. Existing files are an error with OverwriteError; other files
+// that are not written are reported as skipped. In dry-run mode every decision is
+// reported and nothing may be written.
+func (o TransferOptions) checkOverwrite(dst string, srcInfo, dstInfo os.FileInfo, same func() (bool, error)) (bool, error) {
+	if dstInfo == nil {
+		if o.DryRun {
+			o.println(i18n.TWith("ssh.dryrun.create", map[string]interface{}{"Path": dst}))
+		}
+		return !o.DryRun, nil
+	}
+
+	write := false
+	reason := ""
+	switch o.policy() {
+	case OverwriteError:
+		if o.DryRun {
+			o.println(i18n.TWith("ssh.dryrun.exists", map[string]interface{}{"Path": dst}))
+			return false, nil
+		}
+		return false, i18n.ErrorWith("ssh.error.destination.exists", map[string]interface{}{"Path": dst}, fmt.Errorf("file exists"))
+	case OverwriteAlways:
+		write = true
+	case OverwriteNever:
+		reason = "ssh.overwrite.reason.exists"
+	case OverwriteIfNewer:
+		write = srcInfo.ModTime().After(dstInfo.ModTime())
+		reason = "ssh.overwrite.reason.not.newer"
+	case OverwriteIfDifferent:
+		write = srcInfo.Size() != dstInfo.Size()
+		if !write {
+			identical, err := same()
+			if err != nil {
+				return false, i18n.ErrorWith("ssh.error.compare", map[string]interface{}{"Path": dst, "Error": err}, err)
+			}
+			write = !identical
+		}
+		reason = "ssh.overwrite.reason.identical"
+	case OverwriteAsk:
+		if o.DryRun {
+			o.println(i18n.TWith("ssh.dryrun.ask", map[string]interface{}{"Path": dst}))
+			return false, nil
+		}
+		write = o.ask(dst)
+		reason = "ssh.overwrite.reason.declined"
+	}
+
+	if write {
+		if o.DryRun {
+			o.println(i18n.TWith("ssh.dryrun.overwrite", map[string]interface{}{"Path": dst}))
+		}
+		return !o.DryRun, nil
+	}
+
+	key := "ssh.skipped"
+	if o.DryRun {
+		key = "ssh.dryrun.skip"
+	}
+	o.println(i18n.TWith(key, map[string]interface{}{"Path": dst, "Reason": i18n.T(reason)}))
+	return false, nil
+}
+
+// ask prompts whether dst should be overwritten. An "all" or "skip all" answer is
+// remembered for the rest of the transfer.
+func (o TransferOptions) ask(dst string) bool {
+	p := o.prompt
+	if p == nil {
+		p = &overwritePrompt{}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.answer != "" {
+		return p.answer == OverwriteAlways
+	}
+	if p.reader == nil {
+		p.reader = bufio.NewReader(os.Stdin)
+	}
+
+	for {
+		fmt.Print(i18n.TWith("ssh.overwrite.prompt", map[string]interface{}{"Path": dst}))
+		response, err := p.reader.ReadString('\n')
+		if err != nil && response == "" {
+			// No more input: do not overwrite anything else.
+			fmt.Println()
+			p.answer = OverwriteNever
+			return false
+		}
+		switch strings.TrimSpace(strings.ToLower(response)) {
+		case "y", "yes":
+			return true
+		case "n", "no":
+			return false
+		case "a", "all":
+			p.answer = OverwriteAlways
+			return true
+		case "s", "skip":
+			p.answer = OverwriteNever
+			return false
+		}
+	}
+}
+
+// sameChecksum reports whether two files have the same SHA-256 checksum.
+func sameChecksum(a, b func() (string, error)) (bool, error) {
+	sumA, err := a()
+	if err != nil {
+		return false, err
+	}
+	sumB, err := b()
+	if err != nil {
+		return false, err
+	}
+	return sumA == sumB, nil
+}
+
+// localChecksum returns the hex-encoded SHA-256 of a local file.
+func localChecksum(localPath string) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,121 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting the number of bytes per second that
+// transfers may read. A single RateLimiter can be shared by concurrent transfers,
+// in which case the limit applies to their combined throughput.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second, also the bucket size
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing bytesPerSecond bytes per second.
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	return &RateLimiter{
+		rate:   float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until n more bytes may be transferred. Tokens are reserved before
+// sleeping, so concurrent callers are served in turn instead of all waking at once.
+func (l *RateLimiter) wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	time.Sleep(delay)
+}
+
+// Reader wraps r so that reads from it are throttled by the limiter.
+func (l *RateLimiter) Reader(r io.Reader) io.Reader {
+	return &rateLimitedReader{r: r, limiter: l}
+}
+
+type rateLimitedReader struct {
+	r       io.Reader
+	limiter *RateLimiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	// Keep chunks small so throughput stays smooth at low rates.
+	chunk := 32 * 1024
+	if r.limiter.rate < float64(chunk) {
+		chunk = int(r.limiter.rate)
+	}
+	if chunk < 1 {
+		chunk = 1
+	}
+	if len(p) > chunk {
+		p = p[:chunk]
+	}
+
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.limiter.wait(n)
+	}
+	return n, err
+}
+
+// ParseBandwidth parses a transfer rate such as "10MB/s", "512K" or "1.5GiB/s"
+// and returns it in bytes per second. Units are powers of 1024; the "/s" suffix
+// is optional.
+func ParseBandwidth(s string) (int64, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	value = strings.TrimSuffix(value, "/s")
+
+	multipliers := []struct {
+		suffix string
+		factor float64
+	}{
+		{"gib", 1 << 30}, {"gb", 1 << 30}, {"g", 1 << 30},
+		{"mib", 1 << 20}, {"mb", 1 << 20}, {"m", 1 << 20},
+		{"kib", 1 << 10}, {"kb", 1 << 10}, {"k", 1 << 10},
+		{"b", 1},
+	}
+	factor := 1.0
+	for _, m := range multipliers {
+		if strings.HasSuffix(value, m.suffix) {
+			value = strings.TrimSuffix(value, m.suffix)
+			factor = m.factor
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("invalid bandwidth limit %q", s)
+	}
+	// A rate below one byte per second would become 0, which means unlimited.
+	rate := number * factor
+	if rate < 1 {
+		return 0, fmt.Errorf("invalid bandwidth limit %q", s)
+	}
+	if rate >= math.MaxInt64 {
+		return math.MaxInt64, nil
+	}
+	return int64(rate), nil
+}
@@ -0,0 +1,597 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// sftpShell holds the state of an interactive SFTP session.
+type sftpShell struct {
+	client *sftpConn
+	cwd    string
+}
+
+// sftpShellCommands lists the commands understood by the SFTP shell, used for completion.
+var sftpShellCommands = []string{
+	"bye", "cd", "chmod", "df", "exit", "get", "help", "lcd", "lls", "lpwd",
+	"ls", "mkdir", "put", "pwd", "quit", "rename", "rm", "rmdir",
+}
+
+// SFTPShell starts an interactive SFTP session with the server, similar to OpenSSH's sftp.
+func SFTPShell(conn *config.Connection) error {
+	sftpClient, err := newSFTPConn(conn)
+	if err != nil {
+		return err
+	}
+	defer sftpClient.Close()
+
+	cwd, err := sftpClient.Getwd()
+	if err != nil {
+		return i18n.ErrorWith("sftp.error.getwd", map[string]interface{}{"Error": err}, err)
+	}
+
+	shell := &sftpShell{client: sftpClient, cwd: cwd}
+	return shell.run()
+}
+
+// run reads and executes commands until the user quits or input ends.
+func (s *sftpShell) run() error {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if !s.execute(scanner.Text()) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	term := terminal.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "sftp> ")
+	term.AutoCompleteCallback = s.complete
+
+	for {
+		// Only the line editor needs raw mode; commands print progress bars and
+		// messages that expect a normal terminal.
+		oldState, err := terminal.MakeRaw(fd)
+		if err != nil {
+			return i18n.ErrorWith("ssh.error.raw.terminal", map[string]interface{}{"Error": err}, err)
+		}
+		line, err := term.ReadLine()
+		terminal.Restore(fd, oldState)
+
+		if err == io.EOF {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !s.execute(line) {
+			return nil
+		}
+	}
+}
+
+// execute runs a single command line and reports whether the shell should keep running.
+func (s *sftpShell) execute(line string) bool {
+	args := splitShellArgs(line)
+	if len(args) == 0 {
+		return true
+	}
+
+	var err error
+	switch args[0] {
+	case "exit", "quit", "bye":
+		return false
+	case "help", "?":
+		fmt.Println(i18n.T("sftp.help"))
+	case "pwd":
+		fmt.Println(s.cwd)
+	case "lpwd":
+		var wd string
+		if wd, err = os.Getwd(); err == nil {
+			fmt.Println(wd)
+		}
+	case "cd":
+		err = s.cd(args[1:])
+	case "lcd":
+		err = s.lcd(args[1:])
+	case "ls":
+		err = s.ls(args[1:])
+	case "lls":
+		err = s.lls(args[1:])
+	case "get":
+		err = s.get(args[1:])
+	case "put":
+		err = s.put(args[1:])
+	case "rm":
+		err = s.rm(args[1:])
+	case "rmdir":
+		err = s.rmdir(args[1:])
+	case "mkdir":
+		err = s.mkdir(args[1:])
+	case "rename":
+		err = s.rename(args[1:])
+	case "chmod":
+		err = s.chmod(args[1:])
+	case "df":
+		err = s.df(args[1:])
+	default:
+		err = i18n.Error("sftp.error.unknown.command", map[string]interface{}{"Command": args[0]})
+	}
+
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+	return true
+}
+
+// remotePath resolves p against the remote working directory.
+func (s *sftpShell) remotePath(p string) string {
+	if p == "" {
+		return s.cwd
+	}
+	if path.IsAbs(p) {
+		return path.Clean(p)
+	}
+	return path.Join(s.cwd, p)
+}
+
+// usage returns the error printed when a command is called with the wrong arguments.
+func usage(text string) error {
+	return i18n.Error("sftp.error.usage", map[string]interface{}{"Usage": text})
+}
+
+// takeFlag removes a leading flag such as "-r" from args and reports whether it was present.
+func takeFlag(args []string, flag string) ([]string, bool) {
+	if len(args) > 0 && args[0] == flag {
+		return args[1:], true
+	}
+	return args, false
+}
+
+func (s *sftpShell) cd(args []string) error {
+	if len(args) > 1 {
+		return usage("cd [path]")
+	}
+	target := ""
+	if len(args) == 1 {
+		target = args[0]
+	}
+	dir := s.remotePath(target)
+	if target == "" {
+		home, err := s.client.RealPath(".")
+		if err != nil {
+			return err
+		}
+		dir = home
+	}
+
+	info, err := s.client.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return i18n.Error("ssh.error.target.not.dir", map[string]interface{}{"Path": dir})
+	}
+	s.cwd = dir
+	return nil
+}
+
+func (s *sftpShell) lcd(args []string) error {
+	if len(args) != 1 {
+		return usage("lcd <path>")
+	}
+	return os.Chdir(args[0])
+}
+
+func (s *sftpShell) ls(args []string) error {
+	args, long := takeFlag(args, "-l")
+	if len(args) > 1 {
+		return usage("ls [-l] [path]")
+	}
+	target := ""
+	if len(args) == 1 {
+		target = args[0]
+	}
+	dir := s.remotePath(target)
+
+	var entries []os.FileInfo
+	if hasGlobMeta(dir) {
+		matches, err := s.client.Glob(dir)
+		if err != nil {
+			return err
+		}
+		for _, m := range matches {
+			info, err := s.client.Lstat(m)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, info)
+		}
+	} else {
+		info, err := s.client.Stat(dir)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if entries, err = s.client.ReadDir(dir); err != nil {
+				return err
+			}
+		} else {
+			entries = []os.FileInfo{info}
+		}
+	}
+
+	printFileInfos(entries, long)
+	return nil
+}
+
+func (s *sftpShell) lls(args []string) error {
+	args, long := takeFlag(args, "-l")
+	if len(args) > 1 {
+		return usage("lls [-l] [path]")
+	}
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	entries := []os.FileInfo{info}
+	if info.IsDir() {
+		dirEntries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		entries = entries[:0]
+		for _, e := range dirEntries {
+			if fi, err := e.Info(); err == nil {
+				entries = append(entries, fi)
+			}
+		}
+	}
+
+	printFileInfos(entries, long)
+	return nil
+}
+
+// printFileInfos prints directory entries sorted by name, optionally in long format.
+func printFileInfos(entries []os.FileInfo, long bool) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			name += "/"
+		}
+		if long {
+			fmt.Printf("%s %12d %s %s\n", e.Mode(), e.Size(), e.ModTime().Format("Jan _2 15:04"), name)
+		} else {
+			fmt.Println(name)
+		}
+	}
+}
+
+func (s *sftpShell) get(args []string) error {
+	args, recursive := takeFlag(args, "-r")
+	if len(args) < 1 || len(args) > 2 {
+		return usage("get [-r] <remote-path> [local-path]")
+	}
+	localPath := "."
+	if len(args) == 2 {
+		localPath = args[1]
+	}
+
+	sources, err := expandRemotePaths(s.client, []string{s.remotePath(args[0])})
+	if err != nil {
+		return err
+	}
+	opts := TransferOptions{Recursive: recursive, Force: true}
+	for _, remotePath := range sources {
+		if err := downloadPath(s.client, remotePath, localPath, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sftpShell) put(args []string) error {
+	args, recursive := takeFlag(args, "-r")
+	if len(args) < 1 || len(args) > 2 {
+		return usage("put [-r] <local-path> [remote-path]")
+	}
+	remotePath := s.cwd
+	if len(args) == 2 {
+		remotePath = s.remotePath(args[1])
+	}
+
+	sources, err := expandLocalPaths([]string{args[0]})
+	if err != nil {
+		return err
+	}
+	opts := TransferOptions{Recursive: recursive, Force: true}
+	for _, localPath := range sources {
+		if err := uploadPath(s.client, localPath, remotePath, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sftpShell) rm(args []string) error {
+	args, recursive := takeFlag(args, "-r")
+	if len(args) != 1 {
+		return usage("rm [-r] <path>")
+	}
+	targets, err := expandRemotePaths(s.client, []string{s.remotePath(args[0])})
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if recursive {
+			err = s.client.RemoveAll(target)
+		} else {
+			err = s.client.Remove(target)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sftpShell) rmdir(args []string) error {
+	if len(args) != 1 {
+		return usage("rmdir <path>")
+	}
+	return s.client.RemoveDirectory(s.remotePath(args[0]))
+}
+
+func (s *sftpShell) mkdir(args []string) error {
+	args, parents := takeFlag(args, "-p")
+	if len(args) != 1 {
+		return usage("mkdir [-p] <path>")
+	}
+	if parents {
+		return s.client.MkdirAll(s.remotePath(args[0]))
+	}
+	return s.client.Mkdir(s.remotePath(args[0]))
+}
+
+func (s *sftpShell) rename(args []string) error {
+	if len(args) != 2 {
+		return usage("rename <old-path> <new-path>")
+	}
+	return s.client.Rename(s.remotePath(args[0]), s.remotePath(args[1]))
+}
+
+func (s *sftpShell) chmod(args []string) error {
+	if len(args) != 2 {
+		return usage("chmod <mode> <path>")
+	}
+	mode, err := strconv.ParseUint(args[0], 8, 32)
+	if err != nil {
+		return i18n.Error("sftp.error.invalid.mode", map[string]interface{}{"Mode": args[0]})
+	}
+	targets, err := expandRemotePaths(s.client, []string{s.remotePath(args[1])})
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if err := s.client.Chmod(target, os.FileMode(mode)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sftpShell) df(args []string) error {
+	if len(args) > 1 {
+		return usage("df [path]")
+	}
+	target := ""
+	if len(args) == 1 {
+		target = args[0]
+	}
+	if _, ok := s.client.HasExtension("statvfs@openssh.com"); !ok {
+		return i18n.Error("sftp.error.df.unsupported", nil)
+	}
+
+	stat, err := s.client.StatVFS(s.remotePath(target))
+	if err != nil {
+		return err
+	}
+	total := stat.TotalSpace()
+	free := stat.FreeSpace()
+	avail := stat.Frsize * stat.Bavail
+	used := total - free
+	var percent uint64
+	if total > 0 {
+		percent = used * 100 / total
+	}
+	fmt.Printf("%12s %12s %12s %5s\n", "Size", "Used", "Avail", "Use%")
+	fmt.Printf("%12s %12s %12s %4d%%\n", formatSize(total), formatSize(used), formatSize(avail), percent)
+	return nil
+}
+
+// formatSize formats a byte count using binary units.
+func formatSize(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	div, exp := uint64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// complete implements tab completion of command names and paths for the line editor.
+func (s *sftpShell) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	start := strings.LastIndexAny(line[:pos], " \t") + 1
+	word := line[start:pos]
+	before := strings.Fields(line[:start])
+
+	var candidates []string
+	switch {
+	case len(before) == 0:
+		for _, c := range sftpShellCommands {
+			if strings.HasPrefix(c, word) {
+				candidates = append(candidates, c+" ")
+			}
+		}
+	case isLocalArgument(before):
+		candidates = completeLocalPath(word)
+	default:
+		candidates = s.completeRemotePath(word)
+	}
+
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+	completion := commonPrefix(candidates)
+	if len(completion) <= len(word) {
+		return "", 0, false
+	}
+	return line[:start] + completion + line[pos:], start + len(completion), true
+}
+
+// isLocalArgument reports whether the argument being typed after the given words
+// refers to a local path rather than a remote one.
+func isLocalArgument(before []string) bool {
+	var args []string
+	for _, w := range before[1:] {
+		if !strings.HasPrefix(w, "-") {
+			args = append(args, w)
+		}
+	}
+	switch before[0] {
+	case "lcd", "lls":
+		return true
+	case "put":
+		return len(args) == 0
+	case "get":
+		return len(args) == 1
+	}
+	return false
+}
+
+// completeRemotePath returns the remote entries that start with word.
+func (s *sftpShell) completeRemotePath(word string) []string {
+	dir, prefix := path.Split(word)
+	entries, err := s.client.ReadDir(s.remotePath(dir))
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), prefix) {
+			name := dir + e.Name()
+			if e.IsDir() {
+				name += "/"
+			}
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates
+}
+
+// completeLocalPath returns the local entries that start with word.
+func completeLocalPath(word string) []string {
+	dir, prefix := filepath.Split(word)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), prefix) {
+			name := dir + e.Name()
+			if e.IsDir() {
+				name += string(filepath.Separator)
+			}
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates
+}
+
+// commonPrefix returns the longest prefix shared by all strings.
+func commonPrefix(strs []string) string {
+	prefix := strs[0]
+	for _, s := range strs[1:] {
+		for !strings.HasPrefix(s, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// splitShellArgs splits a command line into words, honouring single and double
+// quotes and backslash escapes.
+func splitShellArgs(line string) []string {
+	var args []string
+	var current strings.Builder
+	inWord := false
+	var quote rune
+
+	for i := 0; i < len(line); i++ {
+		c := rune(line[i])
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				current.WriteByte(line[i])
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == '\\' && i+1 < len(line):
+			i++
+			current.WriteByte(line[i])
+			inWord = true
+		case c == ' ' || c == '\t':
+			if inWord {
+				args = append(args, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteByte(line[i])
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, current.String())
+	}
+	return args
+}
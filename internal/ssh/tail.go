@@ -0,0 +1,155 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"gossh/internal/config"
+	"gossh/internal/i18n"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// TailTarget is a remote file to print or follow.
+type TailTarget struct {
+	Conn *config.Connection
+	Path string
+	// Label is printed in front of every line read from the file.
+	Label string
+}
+
+// TailOptions controls how TailFiles reads remote files.
+type TailOptions struct {
+	// Follow keeps streaming appended lines, reopening the file when it is rotated.
+	Follow bool
+	// Lines is the number of existing lines to print first.
+	Lines int
+	// Color prints each label in a color chosen per connection.
+	Color bool
+}
+
+// tailColors are the ANSI colors cycled through for connection labels.
+var tailColors = []string{"\033[36m", "\033[32m", "\033[33m", "\033[35m", "\033[34m", "\033[31m"}
+
+// TailFiles prints the last lines of one or more remote files and, when following,
+// streams lines appended to them until they all end. Files are read with tail on the
+// server; in follow mode it runs with -F so rotated files are reopened when their
+// inode changes. Connections are established one at a time so that password prompts
+// do not overlap, then all files are streamed concurrently.
+func TailFiles(targets []TailTarget, opts TailOptions) error {
+	clients := make(map[string]*ssh.Client)
+	colors := make(map[string]string)
+	defer func() {
+		for _, client := range clients {
+			client.Close()
+		}
+	}()
+
+	for _, t := range targets {
+		if _, ok := clients[t.Conn.Name]; ok {
+			continue
+		}
+		client, err := newClient(t.Conn)
+		if err != nil {
+			return err
+		}
+		clients[t.Conn.Name] = client
+		colors[t.Conn.Name] = tailColors[(len(clients)-1)%len(tailColors)]
+	}
+
+	var printMu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make([]error, len(targets))
+
+	for i, t := range targets {
+		prefix := "[" + t.Label + "] "
+		if opts.Color {
+			prefix = colors[t.Conn.Name] + prefix + "\033[0m"
+		}
+
+		wg.Add(1)
+		go func(i int, t TailTarget, prefix string) {
+			defer wg.Done()
+			errs[i] = tailFile(clients[t.Conn.Name], t.Path, prefix, opts, &printMu)
+			if errs[i] != nil {
+				printMu.Lock()
+				fmt.Fprintf(os.Stderr, "%s%s\n", prefix, errs[i])
+				printMu.Unlock()
+			}
+		}(i, t, prefix)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return i18n.Error("tail.error.all.failed", nil)
+}
+
+// tailFile runs tail for a single remote file and copies its output line by line.
+func tailFile(client *ssh.Client, remotePath, prefix string, opts TailOptions, printMu *sync.Mutex) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.session", map[string]interface{}{"Error": err}, err)
+	}
+	defer session.Close()
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	command := fmt.Sprintf("tail -n %d", opts.Lines)
+	if opts.Follow {
+		command += " -F"
+	}
+	command += " -- " + shellQuote(remotePath)
+	if err := session.Start(command); err != nil {
+		return i18n.ErrorWith("ssh.error.running.command", map[string]interface{}{"Error": err}, err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		copyLines(os.Stdout, stdout, prefix, printMu)
+	}()
+	go func() {
+		defer wg.Done()
+		copyLines(os.Stderr, stderr, prefix, printMu)
+	}()
+	wg.Wait()
+
+	if err := session.Wait(); err != nil {
+		return i18n.ErrorWith("ssh.error.running.command", map[string]interface{}{"Error": err}, err)
+	}
+	return nil
+}
+
+// copyLines writes each line read from r to w with the given prefix. Lines of any
+// length are passed through, so that r is read until it ends and the remote command
+// never blocks on a full channel.
+func copyLines(w io.Writer, r io.Reader, prefix string, printMu *sync.Mutex) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			printMu.Lock()
+			fmt.Fprintf(w, "%s%s\n", prefix, line)
+			printMu.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
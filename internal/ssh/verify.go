@@ -0,0 +1,182 @@
+package ssh
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"gossh/internal/i18n"
+	"hash"
+	"io"
+	"strings"
+)
+
+// SFTP packet types used by the check-file extension.
+const (
+	sshFxpInit          = 1
+	sshFxpVersion       = 2
+	sshFxpStatus        = 101
+	sshFxpExtended      = 200
+	sshFxpExtendedReply = 201
+)
+
+// verifyChecksum compares the SHA-256 computed while streaming a file with the
+// checksum of the file on the remote server.
+func verifyChecksum(sftpClient *sftpConn, remotePath string, localHash hash.Hash, opts TransferOptions) error {
+	expected := hex.EncodeToString(localHash.Sum(nil))
+
+	actual, err := remoteChecksum(sftpClient, remotePath)
+	if err != nil {
+		return i18n.ErrorWith("ssh.error.checksum.remote", map[string]interface{}{"Path": remotePath, "Error": err}, err)
+	}
+
+	if actual != expected {
+		return i18n.Error("ssh.error.checksum.mismatch", map[string]interface{}{
+			"Path":     remotePath,
+			"Expected": expected,
+			"Actual":   actual,
+		})
+	}
+
+	opts.println(i18n.TWith("ssh.checksum.verified", map[string]interface{}{"Path": remotePath, "Checksum": expected}))
+	return nil
+}
+
+// remoteChecksum returns the hex-encoded SHA-256 of a remote file. It uses the
+// check-file SFTP extension when the server advertises it, and otherwise runs
+// sha256sum (or shasum) on the server.
+func remoteChecksum(sftpClient *sftpConn, remotePath string) (string, error) {
+	if _, ok := sftpClient.HasExtension("check-file"); ok {
+		sum, err := checkFileChecksum(sftpClient, remotePath)
+		if err == nil {
+			return sum, nil
+		}
+	}
+	return execChecksum(sftpClient, remotePath)
+}
+
+// execChecksum computes the checksum of a remote file by running a command on the server.
+func execChecksum(sftpClient *sftpConn, remotePath string) (string, error) {
+	session, err := sftpClient.ssh.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	quoted := shellQuote(remotePath)
+	output, err := session.Output(fmt.Sprintf("sha256sum -- %s 2>/dev/null || shasum -a 256 -- %s", quoted, quoted))
+	if err != nil {
+		return "", err
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum output")
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// checkFileChecksum asks the server for the checksum of a remote file using the
+// check-file-name request of the SFTP check-file extension. pkg/sftp does not expose
+// extended requests, so this speaks the protocol on a separate SFTP channel.
+func checkFileChecksum(sftpClient *sftpConn, remotePath string) (string, error) {
+	session, err := sftpClient.ssh.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+
+	w, err := session.StdinPipe()
+	if err != nil {
+		return "", err
+	}
+	r, err := session.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return "", err
+	}
+
+	version := binary.BigEndian.AppendUint32(nil, 3)
+	if err := writeSFTPPacket(w, sshFxpInit, version); err != nil {
+		return "", err
+	}
+	if typ, _, err := readSFTPPacket(r); err != nil {
+		return "", err
+	} else if typ != sshFxpVersion {
+		return "", fmt.Errorf("unexpected SFTP packet type %d", typ)
+	}
+
+	var req []byte
+	req = binary.BigEndian.AppendUint32(req, 1) // request id
+	req = appendSFTPString(req, "check-file-name")
+	req = appendSFTPString(req, remotePath)
+	req = appendSFTPString(req, "sha256")
+	req = binary.BigEndian.AppendUint64(req, 0) // start offset
+	req = binary.BigEndian.AppendUint64(req, 0) // length, 0 means the whole file
+	req = binary.BigEndian.AppendUint32(req, 0) // block size, 0 means a single hash
+	if err := writeSFTPPacket(w, sshFxpExtended, req); err != nil {
+		return "", err
+	}
+
+	typ, payload, err := readSFTPPacket(r)
+	if err != nil {
+		return "", err
+	}
+	if typ == sshFxpStatus {
+		return "", fmt.Errorf("check-file request rejected by server")
+	}
+	if typ != sshFxpExtendedReply || len(payload) < 4 {
+		return "", fmt.Errorf("unexpected SFTP packet type %d", typ)
+	}
+
+	// The reply is: uint32 id, [string "check-file"], string hash-algorithm, byte[] hash.
+	rest := payload[4:]
+	algorithm, rest, ok := readSFTPString(rest)
+	if ok && algorithm == "check-file" {
+		algorithm, rest, ok = readSFTPString(rest)
+	}
+	if !ok || algorithm != "sha256" || len(rest) != 32 {
+		return "", fmt.Errorf("unexpected check-file reply")
+	}
+	return hex.EncodeToString(rest), nil
+}
+
+func writeSFTPPacket(w io.Writer, typ byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	packet = append(packet, typ)
+	packet = append(packet, payload...)
+	_, err := w.Write(packet)
+	return err
+}
+
+func readSFTPPacket(r io.Reader) (byte, []byte, error) {
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return 0, nil, err
+	}
+	if length == 0 || length > 256*1024 {
+		return 0, nil, fmt.Errorf("invalid SFTP packet length %d", length)
+	}
+	packet := make([]byte, length)
+	if _, err := io.ReadFull(r, packet); err != nil {
+		return 0, nil, err
+	}
+	return packet[0], packet[1:], nil
+}
+
+func appendSFTPString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func readSFTPString(b []byte) (string, []byte, bool) {
+	if len(b) < 4 {
+		return "", b, false
+	}
+	n := binary.BigEndian.Uint32(b)
+	if uint32(len(b)-4) < n {
+		return "", b, false
+	}
+	return string(b[4 : 4+n]), b[4+n:], true
+}